@@ -0,0 +1,102 @@
+package binding
+
+import (
+	"mime"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/go-playground/validator.v9"
+)
+
+var (
+	fileHeaderType  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+// registerFileValidations registers the validations of the uploaded files,
+// for the *multipart.FileHeader and []*multipart.FileHeader fields:
+//
+//	required            a file is uploaded
+//	maxsize=5MB         the files are not larger than the size, in bytes or with a KB, MB or GB suffix
+//	filetype=image/png  the media type of the files, as declared by the client, is one of the
+//	                    space-separated types, e.g. "filetype=image/* application/pdf"
+//
+// The number of files of a slice can be validated with min and max. The validations
+// of an optional *multipart.FileHeader must follow omitempty, e.g. "omitempty,maxsize=1MB".
+func registerFileValidations(v *validator.Validate) {
+	// the validator does not run the tags of struct fields, but those of their fields:
+	// the files are validated as slices
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		file := field.Interface().(multipart.FileHeader)
+		return []*multipart.FileHeader{&file}
+	}, multipart.FileHeader{})
+
+	v.RegisterValidation("maxsize", func(fl validator.FieldLevel) bool {
+		files, ok := uploadedFiles(fl)
+		max, err := parseSize(fl.Param())
+		if !ok || err != nil {
+			return false
+		}
+		for _, file := range files {
+			if file.Size > max {
+				return false
+			}
+		}
+		return true
+	})
+	v.RegisterValidation("filetype", func(fl validator.FieldLevel) bool {
+		files, ok := uploadedFiles(fl)
+		if !ok {
+			return false
+		}
+		for _, file := range files {
+			if !MatchMediaType(file.Header.Get("Content-Type"), strings.Fields(fl.Param())) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// uploadedFiles returns the files of a validated field, and false if it is not a file field.
+func uploadedFiles(fl validator.FieldLevel) ([]*multipart.FileHeader, bool) {
+	files, ok := fl.Field().Interface().([]*multipart.FileHeader)
+	return files, ok
+}
+
+// parseSize parses a size in bytes, e.g. "1024", or with a unit, e.g. "512KB" or "5MB".
+func parseSize(param string) (int64, error) {
+	size := strings.ToUpper(strings.TrimSpace(param))
+	var unit int64 = 1
+	for i, suffix := range []string{"KB", "MB", "GB"} {
+		if strings.HasSuffix(size, suffix) {
+			size = strings.TrimSpace(strings.TrimSuffix(size, suffix))
+			unit = 1 << (10 * uint(i+1))
+			break
+		}
+	}
+	n, err := strconv.ParseInt(size, 10, 64)
+	return n * unit, err
+}
+
+// MatchMediaType reports whether a content type matches any of the media types or ranges,
+// e.g. "image/png" or "image/*", ignoring the case and the parameters. The files uploaded
+// without a type are "application/octet-stream".
+func MatchMediaType(contentType string, patterns []string) bool {
+	if len(contentType) == 0 {
+		contentType = "application/octet-stream"
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if mediaType == pattern || strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, pattern[:len(pattern)-1]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package binding
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testFile struct {
+	field, filename, contentType, content string
+}
+
+func newMultipartRequest(files ...testFile) *http.Request {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	mw.WriteField("title", "holidays")
+	for _, f := range files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="`+f.field+`"; filename="`+f.filename+`"`)
+		if len(f.contentType) > 0 {
+			h.Set("Content-Type", f.contentType)
+		}
+		w, _ := mw.CreatePart(h)
+		w.Write([]byte(f.content))
+	}
+	mw.Close()
+
+	req, _ := http.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestMappingFiles(t *testing.T) {
+	for _, b := range []Binding{Form, FormMultipart} {
+		var obj struct {
+			Title   string                  `form:"title"`
+			Cover   *multipart.FileHeader   `form:"cover"`
+			Photos  []*multipart.FileHeader `form:"photo"`
+			Missing *multipart.FileHeader   `form:"missing"`
+		}
+		req := newMultipartRequest(
+			testFile{"cover", "cover.png", "image/png", "cover"},
+			testFile{"photo", "beach.png", "image/png", "sand"},
+			testFile{"photo", "sea.png", "image/png", "waves"},
+		)
+		assert.NoError(t, b.Bind(req, &obj), b.Name())
+
+		assert.Equal(t, "holidays", obj.Title)
+		if assert.NotNil(t, obj.Cover, b.Name()) {
+			assert.Equal(t, "cover.png", obj.Cover.Filename)
+			assert.Equal(t, int64(5), obj.Cover.Size)
+		}
+		if assert.Len(t, obj.Photos, 2, b.Name()) {
+			assert.Equal(t, "beach.png", obj.Photos[0].Filename)
+			assert.Equal(t, "sea.png", obj.Photos[1].Filename)
+		}
+		assert.Nil(t, obj.Missing)
+	}
+}
+
+type fileValidation struct {
+	Avatar    *multipart.FileHeader   `form:"avatar" binding:"required,maxsize=8,filetype=image/*"`
+	Documents []*multipart.FileHeader `form:"doc" binding:"max=2,maxsize=1KB,filetype=application/pdf text/plain"`
+	Optional  *multipart.FileHeader   `form:"optional" binding:"omitempty,maxsize=4"`
+}
+
+func TestValidateFiles(t *testing.T) {
+	avatar := testFile{"avatar", "me.png", "image/png", "12345678"}
+	items := []struct {
+		files []testFile
+		valid bool
+	}{
+		{[]testFile{avatar}, true},
+		{[]testFile{avatar, {"doc", "a.pdf", "application/pdf", "pdf"}, {"doc", "b.txt", "text/plain; charset=utf-8", "txt"}}, true},
+		{[]testFile{avatar, {"optional", "a.txt", "", "1234"}}, true},
+		{[]testFile{}, false},
+		{[]testFile{{"avatar", "me.png", "image/png", "123456789"}}, false},
+		{[]testFile{{"avatar", "me.txt", "text/plain", "text"}}, false},
+		{[]testFile{{"avatar", "me", "", "data"}}, false},
+		{[]testFile{avatar, {"doc", "a.zip", "application/zip", "zip"}}, false},
+		{[]testFile{avatar, {"doc", "a.pdf", "application/pdf", string(make([]byte, 1025))}}, false},
+		{[]testFile{avatar, {"doc", "a.pdf", "application/pdf", ""}, {"doc", "b.pdf", "application/pdf", ""}, {"doc", "c.pdf", "application/pdf", ""}}, false},
+		{[]testFile{avatar, {"optional", "a.txt", "", "12345"}}, false},
+	}
+
+	for i, item := range items {
+		var obj fileValidation
+		err := FormMultipart.Bind(newMultipartRequest(item.files...), &obj)
+		if item.valid {
+			assert.NoError(t, err, "%d", i)
+		} else {
+			assert.Error(t, err, "%d", i)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for param, expected := range map[string]int64{"0": 0, "1024": 1024, "2KB": 2 << 10, "5 mb": 5 << 20, "1GB": 1 << 30} {
+		size, err := parseSize(param)
+		assert.NoError(t, err, param)
+		assert.Equal(t, expected, size, param)
+	}
+	_, err := parseSize("5TB")
+	assert.Error(t, err)
+}
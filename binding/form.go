@@ -1,6 +1,12 @@
 package binding
 
-import "net/http"
+import (
+	"mime/multipart"
+	"net/http"
+)
+
+// defaultMemory is the memory used to parse a multipart form, the files exceeding it being stored on disk.
+const defaultMemory = 32 << 20 // 32 MB
 
 type formBinding struct{}
 type formPostBinding struct{}
@@ -14,8 +20,14 @@ func (formBinding) Bind(req *http.Request, obj interface{}) error {
 	if err := req.ParseForm(); err != nil {
 		return err
 	}
-	req.ParseMultipartForm(32 << 10) // 32 MB TODO:
-	if err := mapForm(obj, req.Form); err != nil {
+	if err := req.ParseMultipartForm(defaultMemory); err != nil && err != http.ErrNotMultipart {
+		return err
+	}
+	var files map[string][]*multipart.FileHeader
+	if req.MultipartForm != nil {
+		files = req.MultipartForm.File
+	}
+	if err := mapForm(obj, req.Form, files); err != nil {
 		return err
 	}
 	return validate(obj)
@@ -29,7 +41,7 @@ func (formPostBinding) Bind(req *http.Request, obj interface{}) error {
 	if err := req.ParseForm(); err != nil {
 		return err
 	}
-	if err := mapForm(obj, req.PostForm); err != nil {
+	if err := mapForm(obj, req.PostForm, nil); err != nil {
 		return err
 	}
 	return validate(obj)
@@ -40,10 +52,10 @@ func (formMultipartBinding) Name() string {
 }
 
 func (formMultipartBinding) Bind(req *http.Request, obj interface{}) error {
-	if err := req.ParseMultipartForm(32 << 10); err != nil {
+	if err := req.ParseMultipartForm(defaultMemory); err != nil {
 		return err
 	}
-	if err := mapForm(obj, req.MultipartForm.Value); err != nil {
+	if err := mapForm(obj, req.MultipartForm.Value, req.MultipartForm.File); err != nil {
 		return err
 	}
 	return validate(obj)
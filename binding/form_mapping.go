@@ -2,11 +2,14 @@ package binding
 
 import (
 	"errors"
+	"mime/multipart"
 	"reflect"
 	"strconv"
 )
 
-func mapForm(ptr interface{}, form map[string][]string) error {
+// mapForm sets the fields of the struct ptr points to from the values of a form,
+// and its *multipart.FileHeader and []*multipart.FileHeader fields from the uploaded files.
+func mapForm(ptr interface{}, form map[string][]string, files map[string][]*multipart.FileHeader) error {
 	typ := reflect.TypeOf(ptr).Elem()
 	val := reflect.ValueOf(ptr).Elem()
 	for i := 0; i < typ.NumField(); i++ {
@@ -25,13 +28,26 @@ func mapForm(ptr interface{}, form map[string][]string) error {
 			// this would not make sense for JSON parsing but it does for a form
 			// since data is flatten
 			if structFieldKind == reflect.Struct {
-				err := mapForm(structField.Addr().Interface(), form)
+				err := mapForm(structField.Addr().Interface(), form, files)
 				if err != nil {
 					return err
 				}
 				continue
 			}
 		}
+		switch typeField.Type {
+		case fileHeaderType:
+			if headers := files[inputFieldName]; len(headers) > 0 {
+				structField.Set(reflect.ValueOf(headers[0]))
+			}
+			continue
+		case fileHeadersType:
+			if headers := files[inputFieldName]; len(headers) > 0 {
+				structField.Set(reflect.ValueOf(headers))
+			}
+			continue
+		}
+
 		inputValue, exists := form[inputFieldName]
 		if !exists {
 			continue
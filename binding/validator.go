@@ -28,6 +28,7 @@ func (v *defaultValidator) lazyinit() {
 	v.once.Do(func() {
 		v.validate = validator.New()
 		v.validate.SetTagName("binding")
+		registerFileValidations(v.validate)
 	})
 }
 
@@ -0,0 +1,301 @@
+package mel
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp/syntax"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// ConflictMode tells how a Router handles the conflicts between its routes,
+// which are detected when the routes are registered, see Router.Conflicts.
+type ConflictMode int
+
+const (
+	// ConflictOverride records the conflicts, and lets a route override a route
+	// registered before with the same pattern. It is the default mode.
+	ConflictOverride ConflictMode = iota
+	// ConflictReport also logs the conflicts as they are detected, in any mode.
+	ConflictReport
+	// ConflictStrict panics on the first conflict.
+	ConflictStrict
+)
+
+const (
+	duplicateConflict = "duplicate" // the same pattern, the route registered last overrides the other one
+	ambiguousConflict = "ambiguous" // the same pattern but the param names, the route registered last is never matched
+	shadowedConflict  = "shadowed"  // the regexps of the params overlap, the route registered first matches the common values
+)
+
+// RouteConflict describes two routes of a method whose patterns match the same paths.
+type RouteConflict struct {
+	Kind        string `json:"kind"` // "duplicate", "ambiguous" or "shadowed"
+	Method      string `json:"method"`
+	Host        string `json:"host,omitempty"`
+	Path        string `json:"path"`   // the pattern registered last
+	Source      string `json:"source"` // the file and line where Path was registered
+	Other       string `json:"other"`  // the pattern registered first
+	OtherSource string `json:"otherSource"`
+}
+
+func (c RouteConflict) String() string {
+	var verb string
+	switch c.Kind {
+	case duplicateConflict:
+		verb = "overrides"
+	case ambiguousConflict:
+		verb = "is ambiguous with"
+	default:
+		verb = "is shadowed by"
+	}
+	return fmt.Sprintf("%s %s%s (%s) %s %s%s (%s)", c.Method, c.Host, c.Path, c.Source, verb, c.Host, c.Other, c.OtherSource)
+}
+
+// Conflicts returns the conflicts between the registered routes, in the order they were detected.
+//
+// Two patterns conflict if they are the same, if they differ only by the names of their params,
+// e.g. "/users/:id" and "/users/:name", or if they differ only by the regexps of their params and
+// the regexps overlap, e.g. "/(:id[0-9]+)" and "/(:name\w+)". The regexps are compared
+// when they repeat a character class, e.g. "[a-z]+" or "\d{4}", other regexps and the
+// constraints are assumed not to overlap.
+func (r *Router) Conflicts() []RouteConflict {
+	return append([]RouteConflict(nil), r.served().conflicts...)
+}
+
+// pattern is a registered pattern, as compared to the patterns registered after it.
+type pattern struct {
+	path     string
+	source   string
+	route    *Route
+	segments []*node
+}
+
+// checkConflicts records the conflicts of a pattern with all the patterns registered before it,
+// and panics in strict mode.
+func (r *Router) checkConflicts(method, path string, segments []*node, route *Route) {
+	table := r.editable()
+	key := patternsKey(r, method, segments)
+	source := callerSource()
+	p := &pattern{
+		path:     path,
+		source:   source,
+		route:    route,
+		segments: segments,
+	}
+
+	patterns := table.patterns[key]
+	for i, other := range patterns {
+		// the variants of an optional pattern are registered for the same route
+		if other.route == route {
+			continue
+		}
+
+		var kind string
+		if patternKey(segments, true) == patternKey(other.segments, true) {
+			if path == other.path {
+				kind = duplicateConflict
+				patterns[i] = p
+				p = nil
+			} else {
+				kind = ambiguousConflict
+			}
+		} else if overlaps(segments, other.segments) {
+			kind = shadowedConflict
+		} else {
+			continue
+		}
+
+		c := RouteConflict{
+			Kind:        kind,
+			Method:      method,
+			Host:        r.host,
+			Path:        path,
+			Source:      source,
+			Other:       other.path,
+			OtherSource: other.source,
+		}
+		switch r.settings().ConflictMode {
+		case ConflictStrict:
+			panic("Route conflict: " + c.String())
+		case ConflictReport:
+			log.Printf("[MEL] [WARNING] Route conflict: %s\n", c)
+		}
+		table.conflicts = append(table.conflicts, c)
+	}
+	if p != nil {
+		table.patterns[key] = append(patterns, p)
+	}
+}
+
+// forgetPattern removes a pattern from the registered patterns, when its route is unregistered.
+func (r *Router) forgetPattern(method, path string, segments []*node) {
+	table := r.editable()
+	key := patternsKey(r, method, segments)
+	patterns := table.patterns[key]
+	for i, p := range patterns {
+		if p.path == path {
+			table.patterns[key] = append(patterns[:i:i], patterns[i+1:]...)
+			return
+		}
+	}
+}
+
+// patternsKey is the key of the patterns of a router and a method which have the same shape as segments.
+func patternsKey(r *Router, method string, segments []*node) string {
+	return r.host + " " + method + " " + patternKey(segments, false)
+}
+
+// patternKey returns the shape of the parsed segments of a pattern, i.e. without the names of the params,
+// and without the regexps and constraints of the params either unless specific is true.
+func patternKey(segments []*node, specific bool) string {
+	var key strings.Builder
+	for _, seg := range segments {
+		switch seg.kind {
+		case staticNode:
+			key.WriteString(seg.segment)
+		case namedNode:
+			key.WriteString("\x00:")
+		case anyNode:
+			key.WriteString("\x00*")
+		case regexNode:
+			key.WriteString("\x00(")
+			if specific {
+				if seg.regexp != nil {
+					key.WriteString(seg.regexp.String())
+				} else {
+					key.WriteString("<" + seg.constraint + ">")
+				}
+			}
+		}
+	}
+	return key.String()
+}
+
+// overlaps reports whether the regexps of two patterns of the same shape
+// match a common value at every position.
+func overlaps(segments, others []*node) bool {
+	for i, seg := range segments {
+		other := others[i]
+		if seg.kind != regexNode {
+			continue
+		}
+		if seg.regexp == nil || other.regexp == nil {
+			if seg.constraint != other.constraint {
+				return false
+			}
+			continue
+		}
+		if seg.regexp.String() == other.regexp.String() {
+			continue
+		}
+
+		ranges, min, max, ok := repeatedClass(seg.regexp.String())
+		if !ok {
+			return false
+		}
+		otherRanges, otherMin, otherMax, ok := repeatedClass(other.regexp.String())
+		if !ok {
+			return false
+		}
+		if !intersectRanges(ranges, otherRanges) {
+			return false
+		}
+		// the values of a param are not empty
+		if otherMin > min {
+			min = otherMin
+		}
+		if min < 1 {
+			min = 1
+		}
+		if max < 0 || otherMax >= 0 && otherMax < max {
+			max = otherMax
+		}
+		if max >= 0 && min > max {
+			return false
+		}
+	}
+	return true
+}
+
+// repeatedClass returns the rune ranges and the length bounds of an anchored param regexp
+// which repeats a character class, e.g. "^([0-9]+)$", the max being -1 if unbounded.
+func repeatedClass(expr string) (ranges []rune, min, max int, ok bool) {
+	// parsePath anchors the regexps as "^(" + re + ")$"
+	expr = strings.TrimSuffix(strings.TrimPrefix(expr, "^("), ")$")
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, 0, 0, false
+	}
+
+	switch re.Op {
+	case syntax.OpPlus:
+		min, max = 1, -1
+	case syntax.OpStar:
+		min, max = 0, -1
+	case syntax.OpQuest:
+		min, max = 0, 1
+	case syntax.OpRepeat:
+		min, max = re.Min, re.Max
+	default:
+		min, max = 1, 1
+		re = &syntax.Regexp{Op: syntax.OpConcat, Sub: []*syntax.Regexp{re}}
+	}
+	if len(re.Sub) != 1 {
+		return nil, 0, 0, false
+	}
+
+	class := re.Sub[0]
+	switch class.Op {
+	case syntax.OpCharClass:
+		ranges = class.Rune
+	case syntax.OpLiteral:
+		if len(class.Rune) != 1 || class.Flags&syntax.FoldCase != 0 {
+			return nil, 0, 0, false
+		}
+		ranges = []rune{class.Rune[0], class.Rune[0]}
+	case syntax.OpAnyCharNotNL:
+		ranges = []rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune}
+	case syntax.OpAnyChar:
+		ranges = []rune{0, unicode.MaxRune}
+	default:
+		return nil, 0, 0, false
+	}
+	return ranges, min, max, true
+}
+
+// intersectRanges reports whether two lists of rune ranges, as pairs of bounds, intersect.
+func intersectRanges(a, b []rune) bool {
+	for i := 0; i+1 < len(a); i += 2 {
+		for j := 0; j+1 < len(b); j += 2 {
+			if a[i] <= b[j+1] && b[j] <= a[i+1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// packageDir is the directory of the package, to skip its frames in callerSource.
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// callerSource returns the file and line of the first caller outside of the package,
+// i.e. where a route is registered.
+func callerSource() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != packageDir || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
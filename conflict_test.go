@@ -0,0 +1,146 @@
+package mel
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteConflicts(t *testing.T) {
+	items := []struct {
+		paths []string
+		kind  string
+		other string // the conflicting pattern, if not the first path
+	}{
+		{[]string{"/users/:id", "/users/:id"}, duplicateConflict, ""},
+		{[]string{"/users/:id", "/users/:name"}, ambiguousConflict, ""},
+		{[]string{"/files/*path", "/files/*rest"}, ambiguousConflict, ""},
+		{[]string{"/users/:id<int>", "/users/:name<int>"}, ambiguousConflict, ""},
+		{[]string{"/users/(:id[0-9]+)", "/users/(:name[0-9]+)"}, ambiguousConflict, ""},
+		{[]string{"/users/(:id[0-9]+)", "/users/(:name\\w+)"}, shadowedConflict, ""},
+		{[]string{"/users/(:id\\d{4})/posts/(:post[a-z]+)", "/users/(:year[0-9]{2,})/posts/(:slug[a-z0-9-]+)"}, shadowedConflict, ""},
+		{[]string{"/users/(:id[0-9]+)", "/users/(:name[a-z]+)"}, "", ""},
+		{[]string{"/users/(:id\\d{4})", "/users/(:id[0-9]{1,3})"}, "", ""},
+		{[]string{"/users/(:id[0-9]+)/posts/(:post[a-z]+)", "/users/(:id[0-9]+)/posts/(:post[0-9]+)"}, "", ""},
+		{[]string{"/users/:id<int>", "/users/:name<alpha>"}, "", ""},
+		{[]string{"/users/(:id[0-9]+)", "/users/(:id(new|edit))"}, "", ""},
+		{[]string{"/users/:id", "/users/:id/posts"}, "", ""},
+		{[]string{"/users/:id", "/users/(:id[0-9]+)"}, "", ""},
+		{[]string{"/users/new", "/users/:id"}, "", ""},
+		{[]string{"/posts(/:page)?", "/posts/:id"}, ambiguousConflict, "/posts/:page"},
+		{[]string{"/archive(/:year(/:month)?)?"}, "", ""},
+	}
+
+	for _, item := range items {
+		router := NewRouter()
+		for _, path := range item.paths {
+			router.Register("GET", path, func() {})
+		}
+		conflicts := router.Conflicts()
+		if item.kind == "" {
+			assert.Empty(t, conflicts, item.paths)
+			continue
+		}
+		if assert.Len(t, conflicts, 1, item.paths) {
+			c := conflicts[0]
+			assert.Equal(t, item.kind, c.Kind, item.paths)
+			assert.Equal(t, "GET", c.Method)
+			if item.other == "" {
+				item.other = item.paths[0]
+			}
+			assert.Equal(t, item.other, c.Other)
+			assert.Equal(t, item.paths[1], c.Path)
+			assert.Contains(t, c.Source, "conflict_test.go:")
+			assert.Contains(t, c.OtherSource, "conflict_test.go:")
+		}
+	}
+}
+
+func TestRouteConflictsOverride(t *testing.T) {
+	router := New()
+	router.Get("/users/:id", func(c *Context) { c.Text(200, "first") })
+	router.Get("/users/:id", func(c *Context) { c.Text(200, "second") })
+	router.Get("/users/:name", func(c *Context) { c.Text(200, "third") })
+
+	conflicts := router.Conflicts()
+	assert.Len(t, conflicts, 2)
+	assert.Equal(t, duplicateConflict, conflicts[0].Kind)
+	assert.Equal(t, ambiguousConflict, conflicts[1].Kind)
+	assert.NotEqual(t, conflicts[0].Source, conflicts[1].Source)
+	// the ambiguous route is reported against the route which overrode the duplicate
+	assert.Equal(t, conflicts[0].Source, conflicts[1].OtherSource)
+
+	w := performRequest(router, "GET", "/users/1")
+	assert.Equal(t, "second", w.Body.String())
+}
+
+func TestRouteConflictsStrict(t *testing.T) {
+	router := NewRouter()
+	router.ConflictMode = ConflictStrict
+	router.Get("/users/:id", func() {})
+	router.Get("/users/:id/posts", func() {})
+
+	defer func() {
+		err := recover()
+		if assert.NotNil(t, err) {
+			msg := err.(string)
+			assert.Contains(t, msg, "GET /users/:name (")
+			assert.Contains(t, msg, "is ambiguous with /users/:id (")
+			assert.Contains(t, msg, "conflict_test.go:")
+		}
+	}()
+	router.Get("/users/:name", func() {})
+}
+
+func TestRouteConflictsHost(t *testing.T) {
+	router := New()
+	router.ConflictMode = ConflictStrict
+	api := router.Host("api.example.com")
+	api.Get("/users/:id", func() {})
+	router.Get("/users/:name", func() {})
+
+	assert.Panics(t, func() {
+		api.Get("/users/:name", func() {})
+	})
+}
+
+func TestRouteConflictsReport(t *testing.T) {
+	// the conflicts are logged in release mode too
+	defer SetMode(Mode())
+	SetMode(ReleaseMode)
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	router := NewRouter()
+	router.ConflictMode = ConflictReport
+	router.Get("/files/*path", func() {})
+	router.Get("/files/*rest", func() {})
+	router.Put("/files/*rest", func() {})
+
+	conflicts := router.Conflicts()
+	if assert.Len(t, conflicts, 1) {
+		assert.Equal(t, ambiguousConflict, conflicts[0].Kind)
+		assert.Regexp(t, `^GET /files/\*rest \(.*conflict_test.go:\d+\) is ambiguous with /files/\*path \(.*conflict_test.go:\d+\)$`, conflicts[0].String())
+		assert.Equal(t, "[MEL] [WARNING] Route conflict: "+conflicts[0].String()+"\n", buf.String())
+	}
+}
+
+func TestRouteConflictsAll(t *testing.T) {
+	router := NewRouter()
+	router.Get("/users/(:id[0-9]+)", func() {})
+	router.Get("/users/(:slug[a-z]+)", func() {})
+	router.Get("/users/(:name\\w+)", func() {})
+
+	// the last pattern is shadowed by both of the others
+	conflicts := router.Conflicts()
+	if assert.Len(t, conflicts, 2) {
+		assert.Equal(t, shadowedConflict, conflicts[0].Kind)
+		assert.Equal(t, "/users/(:id[0-9]+)", conflicts[0].Other)
+		assert.Equal(t, shadowedConflict, conflicts[1].Kind)
+		assert.Equal(t, "/users/(:slug[a-z]+)", conflicts[1].Other)
+	}
+}
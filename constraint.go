@@ -0,0 +1,74 @@
+package mel
+
+import (
+	"strconv"
+)
+
+// Constraint reports whether a value is valid for a route param.
+// A constraint is named in the route pattern after the param, e.g. "/users/:id<int>",
+// and a request whose param does not satisfy it does not match the route,
+// so that it falls through to the other routes, or to 404.
+type Constraint func(value string) bool
+
+var constraints = map[string]Constraint{
+	"int":   isInt,
+	"alpha": isAlphaString,
+	"alnum": isAlphaNumString,
+	"uuid":  isUUID,
+}
+
+// RegisterConstraint registers a Constraint which can be used in route patterns as "<name>",
+// or replaces an existing one, e.g. the builtin "int", "alpha", "alnum" and "uuid".
+// Constraints are resolved when routes are registered, so they must be registered before.
+func RegisterConstraint(name string, c Constraint) {
+	check(len(name) > 0, "Constraint name can not be empty")
+	check(c != nil, "Constraint can not be nil")
+	for i := 0; i < len(name); i++ {
+		check(isAlphaNum(name[i]), "Invalid constraint name: "+name)
+	}
+	constraints[name] = c
+}
+
+func isInt(s string) bool {
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
+
+func isAlphaString(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !('a' <= s[i] && s[i] <= 'z' || 'A' <= s[i] && s[i] <= 'Z') {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+func isAlphaNumString(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !('a' <= s[i] && s[i] <= 'z' || 'A' <= s[i] && s[i] <= 'Z' || isDigit(s[i])) {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+// isUUID accepts the canonical textual form, e.g. "123e4567-e89b-12d3-a456-426655440000".
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			c := s[i]
+			if !(isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
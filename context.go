@@ -1,6 +1,7 @@
 package mel
 
 import (
+    "context"
     "net/http"
     "math"
     "strings"
@@ -30,11 +31,14 @@ func (p *pool) Get() *Context {
 func (p *pool) Put(c *Context) {
     c.Request = nil
     c.Writer.Reset(nil)
-	c.Params = nil
+	c.Params = c.Params[:0] // the buffer of the params is reused by the router
     c.handlers = nil
     c.index = preStartIndex
+    c.route = nil
     c.Keys = nil
     c.Errors = nil
+    c.multipartParsed = false
+    c.multipartErr = nil
 
     p.Pool.Put(c)
 }
@@ -56,10 +60,14 @@ type Context struct {
     Params
     handlers []Handler
     index    int8
+    route    *Route
 
     Keys     map[string]interface{}
     Errors
 
+    multipartParsed bool  // whether the multipart form was parsed, see parseMultipartForm
+    multipartErr    error // the error of parsing the multipart form
+
     *Mel
 }
 
@@ -75,6 +83,69 @@ func (c *Context) reset(w http.ResponseWriter, req *http.Request) {
     c.Request = req
 }
 
+// Pattern returns the pattern of the matched route, e.g. "/users/:id".
+// It returns an empty string if no route matched.
+func (c *Context) Pattern() string {
+    if c.route == nil {
+        return ""
+    }
+    return c.route.path
+}
+
+// RouteName returns the name of the matched route, see Endpoint.Name.
+// It returns an empty string if no route matched, or if the route has no name.
+func (c *Context) RouteName() string {
+    if c.route == nil || c.route.endpoint == nil {
+        return ""
+    }
+    return c.route.endpoint.name
+}
+
+// RouteTags returns a copy of the tags of the matched route, see Endpoint.Tag.
+func (c *Context) RouteTags() []string {
+    if c.route == nil || c.route.endpoint == nil {
+        return nil
+    }
+    return append([]string(nil), c.route.endpoint.tags...)
+}
+
+// HasTag reports whether the matched route has the tag, see Endpoint.Tag.
+func (c *Context) HasTag(tag string) bool {
+    if c.route == nil || c.route.endpoint == nil {
+        return false
+    }
+    return c.route.endpoint.hasTag(tag)
+}
+
+// RouteMeta returns the value of the metadata key of the matched route, see Endpoint.Meta,
+// and whether it exists.
+func (c *Context) RouteMeta(key string) (interface{}, bool) {
+    if c.route == nil || c.route.endpoint == nil {
+        return nil, false
+    }
+    value, ok := c.route.endpoint.meta[key]
+    return value, ok
+}
+
+// Copy returns a copy of the current context that can be safely used outside the request's scope,
+// e.g. when it has to be passed to a goroutine. The copy has no pending handlers
+// and its Writer is detached from the response.
+func (c *Context) Copy() *Context {
+    cp := *c
+    cp.Writer = &responseWriter{}
+    cp.handlers = nil
+    cp.index = abortIndex
+    cp.Params = append(Params(nil), c.Params...)
+    cp.Errors = append(Errors(nil), c.Errors...)
+    if c.Keys != nil {
+        cp.Keys = make(map[string]interface{}, len(c.Keys))
+        for k, v := range c.Keys {
+            cp.Keys[k] = v
+        }
+    }
+    return &cp
+}
+
 // Next executes the pending handlers in the chain inside the calling handler.
 // It should be used only inside middleware.
 func (c *Context) Next() {
@@ -237,7 +308,11 @@ func (c *Context) PostForms(key string) []string {
 // a boolean value whether at least one value exists for the given key.
 func (c *Context) GetPostForms(key string) ([]string, bool) {
     req := c.Request
-    req.ParseMultipartForm(32 << 20) // 32 MB
+    parsed := c.multipartParsed
+    if err := c.parseMultipartForm(); err != nil && err != http.ErrNotMultipart && !parsed {
+        // the values are looked up anyway, the error is left to the error handling middlewares
+        c.Error(err).Type = ErrorTypeBind
+    }
 
     if values := req.PostForm[key]; len(values) > 0 {
         return values, true
@@ -273,6 +348,12 @@ func (c *Context) BindJSON(obj interface{}) error {
 // BindWith binds the passed struct pointer using the specified binding engine.
 // See the binding package.
 func (c *Context) BindWith(obj interface{}, b binding.Binding) error {
+    // the multipart forms are parsed with the limits of the app before they are bound
+    if c.ContentType() == binding.MIMEMultipartPOSTForm {
+        if err := c.parseMultipartForm(); err != nil {
+            return c.uploadFailed(err)
+        }
+    }
     if err := b.Bind(c.Request, obj); err != nil {
         c.AbortWithError(400, err).Type = ErrorTypeBind
         return err
@@ -413,7 +494,12 @@ func (c *Context) File(filePath string) {
 }
 
 // SSE writes a Server-Sent Event into the body stream.
+// It returns http.ErrServerClosed without writing if the app is shutting down,
+// so that event loops can end and let Mel.Shutdown complete.
 func (c *Context) SSE(name string, message interface{}) error {
+    if c.shuttingDown() {
+        return http.ErrServerClosed
+    }
 	event := sse.Event{
         Event: name,
         Data:  message,
@@ -421,13 +507,21 @@ func (c *Context) SSE(name string, message interface{}) error {
     return event.Render(c.Writer)
 }
 
+// Stream calls step repeatedly, flushing the response after each call,
+// until step returns false, the client goes away or the app is shutting down.
 func (c *Context) Stream(step func(w io.Writer) bool) {
     w := c.Writer
     clientGone := w.CloseNotify()
+    var closing <-chan struct{}
+    if c.Mel != nil {
+        closing = c.Mel.closing
+    }
     for {
         select {
         case <-clientGone:
             return
+        case <-closing:
+            return
         default:
             keepOpen := step(w)
             w.Flush()
@@ -438,25 +532,79 @@ func (c *Context) Stream(step func(w io.Writer) bool) {
     }
 }
 
+func (c *Context) shuttingDown() bool {
+    return c.Mel != nil && c.Mel.ShuttingDown()
+}
+
+// contextKey is the type of the keys defined by mel for Context.Value.
+type contextKey struct {
+    name string
+}
+
+// RequestKey is the key for which Context.Value returns the *http.Request.
+var RequestKey = &contextKey{"http-request"}
+
+// SetContext binds ctx to the request, so the Context follows its deadline, cancelation and values.
+// ctx should be derived from c.Request.Context().
+func (c *Context) SetContext(ctx context.Context) {
+    c.Request = c.Request.WithContext(ctx)
+}
+
+// WithTimeout binds a child of the request's context, which is canceled after d, to the request.
+// The returned cancel function should be called to release resources, usually with defer.
+func (c *Context) WithTimeout(d time.Duration) context.CancelFunc {
+    ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+    c.SetContext(ctx)
+    return cancel
+}
+
+// WithDeadline binds a child of the request's context, which is canceled at d, to the request.
+// The returned cancel function should be called to release resources, usually with defer.
+func (c *Context) WithDeadline(d time.Time) context.CancelFunc {
+    ctx, cancel := context.WithDeadline(c.Request.Context(), d)
+    c.SetContext(ctx)
+    return cancel
+}
+
+// Deadline implements the context.Context interface, it returns the deadline of the request's context.
 func (c *Context) Deadline() (deadline time.Time, ok bool) {
-    return
+    if c.Request == nil {
+        return
+    }
+    return c.Request.Context().Deadline()
 }
 
+// Done implements the context.Context interface, it returns a channel that is closed
+// when the client disconnects, the request's context is canceled or its deadline expires.
 func (c *Context) Done() <-chan struct{} {
-    return nil
+    if c.Request == nil {
+        return nil
+    }
+    return c.Request.Context().Done()
 }
 
+// Err implements the context.Context interface, it returns the error of the request's context.
 func (c *Context) Err() error {
-    return nil
+    if c.Request == nil {
+        return nil
+    }
+    return c.Request.Context().Err()
 }
 
+// Value implements the context.Context interface.
+// It returns the request for RequestKey, the value set by Set for a string key,
+// otherwise the value associated with key in the request's context.
 func (c *Context) Value(key interface{}) interface{} {
-    if key == 0 {
+    if key == RequestKey {
         return c.Request
     }
     if keyAsString, ok := key.(string); ok {
-        val, _ := c.Get(keyAsString)
-        return val
+        if val, exists := c.Get(keyAsString); exists {
+            return val
+        }
     }
-    return nil
+    if c.Request == nil {
+        return nil
+    }
+    return c.Request.Context().Value(key)
 }
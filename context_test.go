@@ -16,6 +16,7 @@ import (
 	"github.com/ridewindx/mel/render"
 	"github.com/manucorporat/sse"
 	"time"
+	"context"
 )
 
 func createMultipartRequest() *http.Request {
@@ -665,10 +666,101 @@ func TestContextGolangContext(t *testing.T) {
 	ti, ok := c.Deadline()
 	assert.Equal(t, ti, time.Time{})
 	assert.False(t, ok)
-	assert.Equal(t, c.Value(0), c.Request)
+	assert.Equal(t, c.Value(RequestKey), c.Request)
 	assert.Nil(t, c.Value("foo"))
 
 	c.Set("foo", "bar")
 	assert.Equal(t, c.Value("foo"), "bar")
 	assert.Nil(t, c.Value(1))
 }
+
+type ctxKey string
+
+func TestContextFollowsRequestContext(t *testing.T) {
+	c, _ := CreateTestContext()
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey("user"), "manu"))
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	c.Request = c.Request.WithContext(parent)
+
+	assert.Equal(t, "manu", c.Value(ctxKey("user")))
+	assert.NotNil(t, c.Done())
+	assert.NoError(t, c.Err())
+
+	cancel()
+	<-c.Done()
+	assert.Equal(t, context.Canceled, c.Err())
+}
+
+func TestContextWithTimeout(t *testing.T) {
+	c, _ := CreateTestContext()
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+
+	cancel := c.WithTimeout(10 * time.Millisecond)
+	defer cancel()
+
+	deadline, ok := c.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(10 * time.Millisecond), deadline, 10 * time.Millisecond)
+
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context should be done")
+	}
+	assert.Equal(t, context.DeadlineExceeded, c.Err())
+
+	var _ context.Context = c
+}
+
+func TestContextRouteMetadata(t *testing.T) {
+	router := New()
+	var pattern, name string
+	var tags []string
+	router.Use(func(c *Context) {
+		pattern, name, tags = c.Pattern(), c.RouteName(), c.RouteTags()
+		if permission, ok := c.RouteMeta("permission"); ok && c.Request.Header.Get("X-Role") != permission {
+			c.AbortWithStatus(403)
+			return
+		}
+		c.Next()
+	})
+	router.Get("/users/:id", func(c *Context) {
+		assert.True(t, c.HasTag("users"))
+		assert.False(t, c.HasTag("admin"))
+		c.Text(200, "user")
+	}).Name("user").Tag("users")
+	router.Delete("/users/:id", func(c *Context) {}).Tag("users", "admin").Meta("permission", "admin")
+
+	w := performRequest(router, "GET", "/users/1")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "/users/:id", pattern)
+	assert.Equal(t, "user", name)
+	assert.Equal(t, []string{"users"}, tags)
+
+	w = performRequest(router, "DELETE", "/users/1")
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, "", name)
+	assert.Equal(t, []string{"users", "admin"}, tags)
+
+	// the tags of the route can not be changed through the returned slice
+	tags[0] = "public"
+	performRequest(router, "DELETE", "/users/1")
+	assert.Equal(t, []string{"users", "admin"}, tags)
+
+	req, _ := http.NewRequest("DELETE", "/users/1", nil)
+	req.Header.Set("X-Role", "admin")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	// no route matched
+	w = performRequest(router, "GET", "/posts")
+	assert.Equal(t, 404, w.Code)
+	assert.Equal(t, "", pattern)
+	assert.Nil(t, tags)
+
+	c, _ := CreateTestContext()
+	assert.False(t, c.HasTag("users"))
+	_, ok := c.RouteMeta("permission")
+	assert.False(t, ok)
+}
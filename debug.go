@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"html/template"
 	"log"
+	"sync/atomic"
 )
 
 func init() {
@@ -11,15 +12,15 @@ func init() {
 }
 
 // IsDebugging returns true if the framework is running in debug mode.
-// Use SetMode(gin.Release) to switch to disable the debug mode.
+// Use SetMode(mel.ReleaseMode) to disable the debug mode.
 func IsDebugging() bool {
-	return true
+	return atomic.LoadInt32(&melMode) == debugCode
 }
 
-func debugPrintRoute(httpMethod, absolutePath string, handlers []Handler) {
+// debugPrintRoute prints a registered route, handlers are the middlewares before the handler.
+func debugPrintRoute(httpMethod, absolutePath, handlerName string, handlers []Handler) {
 	if IsDebugging() {
-		nuHandlers := len(handlers)
-		handlerName := nameOfFunction(handlers[nuHandlers-1])
+		nuHandlers := len(handlers) + 1
 		debugPrint("%-6s %-25s --> %s (%d handlers)\n", httpMethod, absolutePath, handlerName, nuHandlers)
 	}
 }
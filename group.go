@@ -30,7 +30,7 @@ func (group *RoutesGroup) Use(middlewares ...Handler) {
 	group.Handlers = append(group.Handlers, middlewares...)
 }
 
-func (group *RoutesGroup) handle(httpMethod, relativePath string, handlers []interface{}) {
+func (group *RoutesGroup) handle(httpMethods interface{}, relativePath string, handlers []interface{}) *Endpoint {
 	num := len(handlers)
 	if num == 0 {
 		panic("Routing target not found")
@@ -44,7 +44,7 @@ func (group *RoutesGroup) handle(httpMethod, relativePath string, handlers []int
 
 	absolutePath := joinPaths(group.BasePath, relativePath)
 	middlewares = group.combineHandlers(middlewares)
-	group.router.Register(httpMethod, absolutePath, target, middlewares...)
+	return group.router.Register(httpMethods, absolutePath, target, middlewares...)
 }
 
 // Handle registers a new request handle and middleware with the given path and method.
@@ -54,55 +54,48 @@ func (group *RoutesGroup) handle(httpMethod, relativePath string, handlers []int
 // This function is intended for bulk loading and to allow the usage of less
 // frequently used, non-standardized or custom methods (e.g. for internal
 // communication with a proxy).
-func (group *RoutesGroup) Handle(httpMethod, relativePath string, handlers ...interface{}) {
+func (group *RoutesGroup) Handle(httpMethod, relativePath string, handlers ...interface{}) *Endpoint {
 	if matches, err := regexp.MatchString("^[A-Z]+$", httpMethod); !matches || err != nil {
 		panic("HTTP method " + httpMethod + " is invalid")
 	}
-	group.handle(httpMethod, relativePath, handlers)
+	return group.handle(httpMethod, relativePath, handlers)
 }
 
-func (group *RoutesGroup) Get(relativePath string, handlers ...interface{}) {
-	group.handle("GET", relativePath, handlers)
+func (group *RoutesGroup) Get(relativePath string, handlers ...interface{}) *Endpoint {
+	return group.handle("GET", relativePath, handlers)
 }
 
-func (group *RoutesGroup) Post(relativePath string, handlers ...interface{}) {
-	group.handle("POST", relativePath, handlers)
+func (group *RoutesGroup) Post(relativePath string, handlers ...interface{}) *Endpoint {
+	return group.handle("POST", relativePath, handlers)
 }
 
-func (group *RoutesGroup) Head(relativePath string, handlers ...interface{}) {
-	group.handle("HEAD", relativePath, handlers)
+func (group *RoutesGroup) Head(relativePath string, handlers ...interface{}) *Endpoint {
+	return group.handle("HEAD", relativePath, handlers)
 }
 
-func (group *RoutesGroup) Delete(relativePath string, handlers ...interface{}) {
-	group.handle("DELETE", relativePath, handlers)
+func (group *RoutesGroup) Delete(relativePath string, handlers ...interface{}) *Endpoint {
+	return group.handle("DELETE", relativePath, handlers)
 }
 
-func (group *RoutesGroup) Put(relativePath string, handlers ...interface{}) {
-	group.handle("PUT", relativePath, handlers)
+func (group *RoutesGroup) Put(relativePath string, handlers ...interface{}) *Endpoint {
+	return group.handle("PUT", relativePath, handlers)
 }
 
-func (group *RoutesGroup) Options(relativePath string, handlers ...interface{}) {
-	group.handle("OPTIONS", relativePath, handlers)
+func (group *RoutesGroup) Options(relativePath string, handlers ...interface{}) *Endpoint {
+	return group.handle("OPTIONS", relativePath, handlers)
 }
 
-func (group *RoutesGroup) Trace(relativePath string, handlers ...interface{}) {
-	group.handle("TRACE", relativePath, handlers)
+func (group *RoutesGroup) Trace(relativePath string, handlers ...interface{}) *Endpoint {
+	return group.handle("TRACE", relativePath, handlers)
 }
 
-func (group *RoutesGroup) Patch(relativePath string, handlers ...interface{}) {
-	group.handle("PATCH", relativePath, handlers)
+func (group *RoutesGroup) Patch(relativePath string, handlers ...interface{}) *Endpoint {
+	return group.handle("PATCH", relativePath, handlers)
 }
 
 // Any registers a route that matches all the HTTP methods.
-func (group *RoutesGroup) Any(relativePath string, handlers ...interface{}) {
-	group.Get(relativePath, handlers...)
-	group.Post(relativePath, handlers...)
-	group.Head(relativePath, handlers...)
-	group.Delete(relativePath, handlers...)
-	group.Put(relativePath, handlers...)
-	group.Options(relativePath, handlers...)
-	group.Trace(relativePath, handlers...)
-	group.Patch(relativePath, handlers...)
+func (group *RoutesGroup) Any(relativePath string, handlers ...interface{}) *Endpoint {
+	return group.handle(Methods, relativePath, handlers)
 }
 
 // StaticFile registers a single route in order to server a single file of the local filesystem.
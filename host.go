@@ -0,0 +1,102 @@
+package mel
+
+import (
+	"strings"
+)
+
+// Host creates a new router group bound to the host pattern, e.g. "api.example.com".
+// A label of the pattern may be a param, e.g. ":tenant.example.com", optionally with a constraint,
+// and the host params are prepended to Context.Params. The port of the request host is ignored.
+//
+// The routes of a host are only matched by requests to a matching host, in the order
+// the hosts were created, and a request whose host matches no host pattern
+// falls back to the routes registered without a host.
+func (group *RoutesGroup) Host(pattern string, handlers ...Handler) *RoutesGroup {
+	return &RoutesGroup{
+		BasePath: group.BasePath,
+		Handlers: group.combineHandlers(handlers),
+		router:   group.router.hostRouter(pattern),
+	}
+}
+
+// hostRouter returns the router bound to the host pattern, creating it if needed.
+func (r *Router) hostRouter(pattern string) *Router {
+	root := r.settings()
+	table := r.editable()
+	pattern = strings.ToLower(pattern)
+	for _, hr := range table.hosts {
+		if hr.host == pattern {
+			return hr
+		}
+	}
+
+	check(len(pattern) > 0, "Host pattern can not be empty")
+	var labels []*node
+	for _, label := range strings.Split(pattern, ".") {
+		check(len(label) > 0, "Invalid host pattern: "+pattern)
+		if label[0] != ':' {
+			check(strings.IndexAny(label, ":()*<>") == -1, "Invalid host pattern: "+pattern)
+			labels = append(labels, &node{kind: staticNode, segment: label})
+			continue
+		}
+		// a param, with its constraint if any
+		nodes := parsePath("/" + label)
+		check(len(nodes) == 2 && nodes[1].kind != staticNode && nodes[1].regexp == nil,
+			"Invalid host pattern: "+pattern)
+		labels = append(labels, nodes[1])
+	}
+
+	hr := &Router{
+		RoutesGroup: RoutesGroup{BasePath: "/"},
+		host:        pattern,
+		hostLabels:  labels,
+		parent:      root,
+	}
+	hr.RoutesGroup.router = hr
+	table.trees[hr] = newTrees()
+	table.hosts = append(table.hosts, hr)
+	return hr
+}
+
+// forHost returns the router bound to the first host pattern matching host, with the host params,
+// or else the router itself.
+func (r *Router) forHost(host string) (*Router, Params) {
+	hosts := r.served().hosts
+	if len(hosts) == 0 {
+		return r, nil
+	}
+
+	if i := strings.LastIndexByte(host, ':'); i > -1 && !strings.Contains(host[i:], "]") {
+		host = host[:i] // strip the port
+	}
+	labels := strings.Split(strings.ToLower(host), ".")
+
+	for _, hr := range hosts {
+		if params, ok := hr.matchHost(labels); ok {
+			return hr, params
+		}
+	}
+	return r, nil
+}
+
+func (r *Router) matchHost(labels []string) (Params, bool) {
+	if len(labels) != len(r.hostLabels) {
+		return nil, false
+	}
+
+	var params Params
+	for i, n := range r.hostLabels {
+		if n.kind == staticNode {
+			if n.segment != labels[i] {
+				return nil, false
+			}
+			continue
+		}
+
+		if len(labels[i]) == 0 || n.validate != nil && !n.validate(labels[i]) {
+			return nil, false
+		}
+		params = append(params, Param{n.segment, labels[i]})
+	}
+	return params, true
+}
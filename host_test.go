@@ -0,0 +1,76 @@
+package mel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostRouting(t *testing.T) {
+	router := New()
+	var global int
+	router.Use(func(c *Context) {
+		global++
+		c.Next()
+	})
+	router.Get("/", func(c *Context) { c.Text(200, "default") })
+
+	api := router.Host("api.example.com")
+	api.Get("/", func(c *Context) { c.Text(200, "api") })
+	api.Group("/v1").Get("/users/:id", func(c *Context) { c.Text(200, "api user %s", c.Param("id")) })
+
+	tenant := router.Host(":tenant.example.com")
+	tenant.Get("/", func(c *Context) { c.Text(200, "tenant %s", c.Param("tenant")) })
+
+	w := performRequest(router, "GET", "http://api.example.com/")
+	assert.Equal(t, "api", w.Body.String())
+
+	w = performRequest(router, "GET", "http://API.example.com:8080/v1/users/42")
+	assert.Equal(t, "api user 42", w.Body.String())
+
+	w = performRequest(router, "GET", "http://acme.example.com/")
+	assert.Equal(t, "tenant acme", w.Body.String())
+
+	w = performRequest(router, "GET", "http://acme.example.com/v1/users/42")
+	assert.Equal(t, 404, w.Code)
+
+	w = performRequest(router, "GET", "http://example.com/")
+	assert.Equal(t, "default", w.Body.String())
+
+	w = performRequest(router, "GET", "http://a.b.example.com/")
+	assert.Equal(t, "default", w.Body.String())
+
+	assert.Equal(t, 6, global)
+	assert.True(t, router.Host("API.example.com").router == api.router)
+}
+
+func TestHostParams(t *testing.T) {
+	router := New()
+	router.Host(":tenant<alpha>.example.com").Get("/users/:id<int>", func(c *Context) {
+		c.JSON(200, c.Params)
+	})
+
+	w := performRequest(router, "GET", "http://acme.example.com/users/42")
+	assert.Equal(t, `[{"Key":":tenant","Value":"acme"},{"Key":":id","Value":"42"}]`+"\n", w.Body.String())
+
+	w = performRequest(router, "GET", "http://acme42.example.com/users/42")
+	assert.Equal(t, 404, w.Code)
+
+	assert.Panics(t, func() { router.Host("api..example.com") })
+	assert.Panics(t, func() { router.Host("(:tenant[a-z]+).example.com") })
+}
+
+func TestHostRoutes(t *testing.T) {
+	router := New()
+	router.Get("/", func() {})
+	router.Host("api.example.com").Get("/", func() {}).Name("api")
+
+	routes := router.Routes()
+	assert.Len(t, routes, 2)
+	assert.Equal(t, "", routes[0].Host)
+	assert.Equal(t, "api.example.com", routes[1].Host)
+
+	url, err := router.URL("api")
+	assert.NoError(t, err)
+	assert.Equal(t, "/", url)
+}
@@ -0,0 +1,269 @@
+package mel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	green   = string([]byte{27, 91, 57, 55, 59, 52, 50, 109})
+	white   = string([]byte{27, 91, 57, 48, 59, 52, 55, 109})
+	yellow  = string([]byte{27, 91, 57, 55, 59, 52, 51, 109})
+	red     = string([]byte{27, 91, 57, 55, 59, 52, 49, 109})
+	blue    = string([]byte{27, 91, 57, 55, 59, 52, 52, 109})
+	magenta = string([]byte{27, 91, 57, 55, 59, 52, 53, 109})
+	cyan    = string([]byte{27, 91, 57, 55, 59, 52, 54, 109})
+	reset   = string([]byte{27, 91, 48, 109})
+)
+
+// LogParams holds the information about a request that a LogFormatter formats.
+type LogParams struct {
+	Time      time.Time // when the request was received
+	Latency   time.Duration
+	Method    string
+	Path      string // the request path, with the raw query if any
+	Pattern   string // the pattern of the matched route, empty if no route matched
+	Proto     string
+	Status    int
+	Size      int
+	ClientIP  string
+	User      string // the user of the basic authentication
+	Referer   string
+	UserAgent string
+	Errors    Errors
+
+	// Colorized is true if the output may contain ANSI colors, i.e. in debug mode.
+	Colorized bool
+}
+
+// LogFormatter formats a line of the access log, including the trailing newline.
+type LogFormatter func(params *LogParams) string
+
+// LoggerConfig configures the Logger middleware.
+type LoggerConfig struct {
+	// Formatter formats the log lines, DefaultLogFormatter by default.
+	Formatter LogFormatter
+
+	// Output is where the log lines are written, os.Stdout by default.
+	Output io.Writer
+
+	// SkipPaths are request paths which are not logged, e.g. health checks.
+	SkipPaths []string
+
+	// DisableColors disables the colors in debug mode.
+	DisableColors bool
+}
+
+// Logger returns a middleware that writes an access log line for every request to os.Stdout.
+func Logger() Handler {
+	return LoggerWithConfig(LoggerConfig{})
+}
+
+// LoggerWithWriter returns a Logger middleware that writes to out, and does not log the skipPaths.
+func LoggerWithWriter(out io.Writer, skipPaths ...string) Handler {
+	return LoggerWithConfig(LoggerConfig{
+		Output:    out,
+		SkipPaths: skipPaths,
+	})
+}
+
+// LoggerWithFormatter returns a Logger middleware that formats the log lines with f.
+func LoggerWithFormatter(f LogFormatter) Handler {
+	return LoggerWithConfig(LoggerConfig{Formatter: f})
+}
+
+// LoggerWithConfig returns a Logger middleware with the given config.
+func LoggerWithConfig(config LoggerConfig) Handler {
+	formatter := config.Formatter
+	if formatter == nil {
+		formatter = DefaultLogFormatter
+	}
+
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	var mu sync.Mutex
+
+	var skip map[string]struct{}
+	if len(config.SkipPaths) > 0 {
+		skip = make(map[string]struct{}, len(config.SkipPaths))
+		for _, path := range config.SkipPaths {
+			skip[path] = struct{}{}
+		}
+	}
+
+	return func(c *Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		raw := c.Request.URL.RawQuery
+		method := c.Request.Method
+
+		c.Next()
+
+		if _, ok := skip[path]; ok {
+			return
+		}
+
+		if raw != "" {
+			path = path + "?" + raw
+		}
+		user, _, _ := c.Request.BasicAuth()
+		params := &LogParams{
+			Time:      start,
+			Latency:   time.Since(start),
+			Method:    method,
+			Path:      path,
+			Pattern:   c.Pattern(),
+			Proto:     c.Request.Proto,
+			Status:    c.Writer.Status(),
+			Size:      c.Writer.Size(),
+			ClientIP:  c.ClientIP(),
+			User:      user,
+			Referer:   c.Request.Referer(),
+			UserAgent: c.Request.UserAgent(),
+			Errors:    c.Errors,
+			Colorized: IsDebugging() && !config.DisableColors,
+		}
+		if params.Status == 0 {
+			params.Status = 200
+		}
+
+		line := formatter(params)
+		mu.Lock()
+		io.WriteString(out, line)
+		mu.Unlock()
+	}
+}
+
+// DefaultLogFormatter is the human readable format used by Logger by default.
+//
+//	[MEL] 2017/05/08 - 10:02:31 | 200 |     1.220ms |       127.0.0.1 | GET     /users/42 (/users/:id)
+var DefaultLogFormatter LogFormatter = func(p *LogParams) string {
+	var statusColor, methodColor, resetColor string
+	if p.Colorized {
+		statusColor = colorForStatus(p.Status)
+		methodColor = colorForMethod(p.Method)
+		resetColor = reset
+	}
+
+	var pattern string
+	if len(p.Pattern) > 0 && p.Pattern != p.Path {
+		pattern = " (" + p.Pattern + ")"
+	}
+
+	return fmt.Sprintf("[MEL] %v |%s %3d %s| %13v | %15s |%s %-7s %s %s%s\n%s",
+		p.Time.Format("2006/01/02 - 15:04:05"),
+		statusColor, p.Status, resetColor,
+		p.Latency,
+		p.ClientIP,
+		methodColor, p.Method, resetColor,
+		p.Path, pattern,
+		p.Errors.ByType(ErrorTypePrivate).String(),
+	)
+}
+
+// CommonLogFormatter formats the log lines in the Common Log Format.
+//
+//	127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+var CommonLogFormatter LogFormatter = func(p *LogParams) string {
+	return commonLog(p) + "\n"
+}
+
+// CombinedLogFormatter formats the log lines in the Combined Log Format,
+// i.e. the Common Log Format with the referer and the user agent.
+var CombinedLogFormatter LogFormatter = func(p *LogParams) string {
+	return commonLog(p) + " " + strconv.Quote(p.Referer) + " " + strconv.Quote(p.UserAgent) + "\n"
+}
+
+func commonLog(p *LogParams) string {
+	user := p.User
+	if user == "" {
+		user = "-"
+	}
+	size := "-"
+	if p.Size > 0 {
+		size = strconv.Itoa(p.Size)
+	}
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s",
+		p.ClientIP, user, p.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		p.Method, p.Path, p.Proto, p.Status, size)
+}
+
+type jsonLogLine struct {
+	Time      string   `json:"time"`
+	Method    string   `json:"method"`
+	Path      string   `json:"path"`
+	Pattern   string   `json:"pattern,omitempty"`
+	Proto     string   `json:"proto"`
+	Status    int      `json:"status"`
+	Size      int      `json:"size"`
+	Latency   float64  `json:"latency_ms"`
+	ClientIP  string   `json:"client_ip"`
+	User      string   `json:"user,omitempty"`
+	Referer   string   `json:"referer,omitempty"`
+	UserAgent string   `json:"user_agent,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// JSONLogFormatter formats every log line as a JSON object.
+var JSONLogFormatter LogFormatter = func(p *LogParams) string {
+	line, err := json.Marshal(&jsonLogLine{
+		Time:      p.Time.Format(time.RFC3339Nano),
+		Method:    p.Method,
+		Path:      p.Path,
+		Pattern:   p.Pattern,
+		Proto:     p.Proto,
+		Status:    p.Status,
+		Size:      p.Size,
+		Latency:   float64(p.Latency) / float64(time.Millisecond),
+		ClientIP:  p.ClientIP,
+		User:      p.User,
+		Referer:   p.Referer,
+		UserAgent: p.UserAgent,
+		Errors:    p.Errors.Errors(),
+	})
+	if err != nil {
+		return fmt.Sprintf("{\"error\":%q}\n", err.Error())
+	}
+	return string(line) + "\n"
+}
+
+func colorForStatus(code int) string {
+	switch {
+	case code >= 200 && code < 300:
+		return green
+	case code >= 300 && code < 400:
+		return white
+	case code >= 400 && code < 500:
+		return yellow
+	default:
+		return red
+	}
+}
+
+func colorForMethod(method string) string {
+	switch method {
+	case "GET":
+		return blue
+	case "POST":
+		return cyan
+	case "PUT":
+		return yellow
+	case "DELETE":
+		return red
+	case "PATCH":
+		return green
+	case "HEAD":
+		return magenta
+	case "OPTIONS":
+		return white
+	default:
+		return reset
+	}
+}
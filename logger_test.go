@@ -0,0 +1,151 @@
+package mel
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	defer SetMode(Mode())
+	SetMode(TestMode)
+
+	buffer := new(bytes.Buffer)
+	router := New()
+	router.Use(LoggerWithWriter(buffer))
+	router.Get("/users/:id", func(c *Context) { c.Text(200, "user") })
+	router.Post("/example", func(c *Context) {
+		c.Error(errors.New("something wrong"))
+		c.AbortWithStatus(400)
+	})
+
+	performRequest(router, "GET", "/users/42?fields=name")
+	assert.Contains(t, buffer.String(), "[MEL] ")
+	assert.Contains(t, buffer.String(), "200")
+	assert.Contains(t, buffer.String(), "GET")
+	assert.Contains(t, buffer.String(), "/users/42?fields=name (/users/:id)")
+	assert.NotContains(t, buffer.String(), green)
+
+	buffer.Reset()
+	performRequest(router, "POST", "/example")
+	assert.Contains(t, buffer.String(), "400")
+	assert.Contains(t, buffer.String(), "POST")
+	assert.Contains(t, buffer.String(), "/example")
+	assert.Contains(t, buffer.String(), "Error #01: something wrong")
+
+	buffer.Reset()
+	performRequest(router, "GET", "/notfound")
+	assert.Contains(t, buffer.String(), "404")
+	assert.Contains(t, buffer.String(), "/notfound")
+}
+
+func TestLoggerColorsInDebugMode(t *testing.T) {
+	defer SetMode(Mode())
+	SetMode(DebugMode)
+
+	buffer := new(bytes.Buffer)
+	router := New()
+	router.Use(LoggerWithWriter(buffer))
+	router.Get("/example", func(c *Context) {})
+
+	performRequest(router, "GET", "/example")
+	assert.Contains(t, buffer.String(), green)
+	assert.Contains(t, buffer.String(), blue)
+
+	buffer.Reset()
+	router = New()
+	router.Use(LoggerWithConfig(LoggerConfig{Output: buffer, DisableColors: true}))
+	router.Get("/example", func(c *Context) {})
+
+	performRequest(router, "GET", "/example")
+	assert.NotContains(t, buffer.String(), green)
+}
+
+func TestLoggerSkipPaths(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	router := New()
+	router.Use(LoggerWithWriter(buffer, "/healthz"))
+	router.Get("/healthz", func(c *Context) {})
+	router.Get("/example", func(c *Context) {})
+
+	performRequest(router, "GET", "/healthz")
+	assert.Empty(t, buffer.String())
+
+	performRequest(router, "GET", "/example")
+	assert.Contains(t, buffer.String(), "/example")
+}
+
+func TestLoggerCustomFormatter(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	router := New()
+	router.Use(LoggerWithConfig(LoggerConfig{
+		Output: buffer,
+		Formatter: func(p *LogParams) string {
+			return p.Method + " " + p.Pattern + " " + p.ClientIP + "\n"
+		},
+	}))
+	router.Get("/users/:id", func(c *Context) {})
+
+	req, _ := http.NewRequest("GET", "/users/42", nil)
+	req.Header.Set("X-Real-IP", "10.0.0.1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "GET /users/:id 10.0.0.1\n", buffer.String())
+}
+
+func testLogParams() *LogParams {
+	return &LogParams{
+		Time:      time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		Latency:   1500 * time.Microsecond,
+		Method:    "GET",
+		Path:      "/apache_pb.gif",
+		Pattern:   "/*file",
+		Proto:     "HTTP/1.0",
+		Status:    200,
+		Size:      2326,
+		ClientIP:  "127.0.0.1",
+		User:      "frank",
+		Referer:   "http://www.example.com/start.html",
+		UserAgent: "Mozilla/4.08",
+	}
+}
+
+func TestCommonLogFormatter(t *testing.T) {
+	p := testLogParams()
+	assert.Equal(t, "127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] \"GET /apache_pb.gif HTTP/1.0\" 200 2326\n",
+		CommonLogFormatter(p))
+
+	p.User = ""
+	p.Size = 0
+	assert.Equal(t, "127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] \"GET /apache_pb.gif HTTP/1.0\" 200 -\n",
+		CommonLogFormatter(p))
+}
+
+func TestCombinedLogFormatter(t *testing.T) {
+	assert.Equal(t, "127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] \"GET /apache_pb.gif HTTP/1.0\" 200 2326"+
+		" \"http://www.example.com/start.html\" \"Mozilla/4.08\"\n",
+		CombinedLogFormatter(testLogParams()))
+}
+
+func TestJSONLogFormatter(t *testing.T) {
+	p := testLogParams()
+	p.Errors = Errors{{Err: errors.New("oops")}}
+
+	line := JSONLogFormatter(p)
+	assert.Equal(t, byte('\n'), line[len(line)-1])
+
+	var obj map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(line), &obj))
+	assert.Equal(t, "GET", obj["method"])
+	assert.Equal(t, "/*file", obj["pattern"])
+	assert.Equal(t, float64(200), obj["status"])
+	assert.Equal(t, float64(2326), obj["size"])
+	assert.Equal(t, 1.5, obj["latency_ms"])
+	assert.Equal(t, "127.0.0.1", obj["client_ip"])
+	assert.Equal(t, []interface{}{"oops"}, obj["errors"])
+}
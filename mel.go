@@ -4,7 +4,12 @@ import (
 	"html/template"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"github.com/gin-gonic/gin/binding"
 )
 
@@ -22,14 +27,29 @@ type Mel struct {
 	noRoute     []Handler
 	noMethod    []Handler
 
-	RedirectTrailingSlash  bool
-	RedirectFixedPath      bool
-	HandleMethodNotAllowed bool
-	ForwardedByClientIP    bool
+	RedirectTrailingSlash   bool
+	RedirectFixedPath       bool
+	RedirectCaseInsensitive bool // redirect the paths which match a route ignoring the case to the casing of the route
+	HandleMethodNotAllowed  bool // answer 405 with the Allow header, instead of 404
+	HandleOPTIONS           bool // answer OPTIONS requests which match no route with the Allow header
+	ForwardedByClientIP     bool
+	UseRawPath              bool // match the path with its encoded slashes, e.g. "%2F", which the params may then contain
+
+	MaxMultipartMemory int64        // the memory used to parse a multipart form, the files exceeding it are stored on disk
+	Uploads            UploadLimits // the limits of the files uploaded in multipart forms
 
 	Template *template.Template
 
 	vars map[string]interface{}
+
+	servers    map[*http.Server]struct{}
+	serversMu  sync.Mutex
+	active     int64         // number of requests being handled
+	closing    chan struct{} // closed when Shutdown is called
+	closeOnce  sync.Once
+	onStart    []func() error
+	onShutdown []func()
+	hooksOnce  sync.Once // the OnShutdown hooks are called once
 }
 
 func New() *Mel {
@@ -39,7 +59,11 @@ func New() *Mel {
 		RedirectTrailingSlash:  true,
 		RedirectFixedPath:      false,
 		HandleMethodNotAllowed: false,
+		HandleOPTIONS:          true,
 		ForwardedByClientIP:    true,
+		MaxMultipartMemory:     defaultMultipartMemory,
+		servers:                make(map[*http.Server]struct{}),
+		closing:                make(chan struct{}),
 	}
 
 	mel.pool = newPool(mel)
@@ -47,16 +71,43 @@ func New() *Mel {
 	return mel
 }
 
+// Default returns a new app with the Logger and Recovery middlewares already attached.
+func Default() *Mel {
+	mel := New()
+	mel.Use(Logger(), Recovery())
+	return mel
+}
+
 func (mel *Mel) SetTemplate(template *template.Template) {
+	debugPrintLoadTemplate(template)
 	mel.Template = template
 }
 
+// LoadTemplateGlob parses the templates matched by pattern, with the functions of FuncMap.
 func (mel *Mel) LoadTemplateGlob(pattern string) {
-	mel.SetTemplate(template.Must(template.ParseGlob(pattern)))
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		panic(err)
+	}
+	check(len(files) > 0, "No template files match "+pattern)
+	mel.LoadTemplates(files...)
 }
 
+// LoadTemplates parses the template files, with the functions of FuncMap.
 func (mel *Mel) LoadTemplates(files ...string) {
-	mel.SetTemplate(template.Must(template.ParseFiles(files...)))
+	check(len(files) > 0, "No template files")
+	tmpl := template.New(filepath.Base(files[0])).Funcs(mel.FuncMap())
+	mel.SetTemplate(template.Must(tmpl.ParseFiles(files...)))
+}
+
+// FuncMap returns the functions the app provides to templates:
+//     url: builds the URL of a named route, e.g. {{url "user" "id" .ID}}, see Router.URL
+// Templates passed to SetTemplate should be created with them, e.g.
+//     template.New("").Funcs(app.FuncMap()).Parse(text)
+func (mel *Mel) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"url": mel.URL,
+	}
 }
 
 // NoRoute sets handlers for requests that match no route.
@@ -91,22 +142,26 @@ func (mel *Mel) rebuildNoMethodHandlers() {
 }
 
 // Run attaches `mel` to a http.Server and starts listening and serving HTTP requests.
-// Note: this method will block the calling goroutine indefinitely unless an error happens.
+// Note: this method will block the calling goroutine until Shutdown is called or an error happens.
 func (mel *Mel) Run(addrs ...string) (err error) {
 	addr := resolveAddress(addrs)
 
 	debugPrint("Listening and serving HTTP on %s\n", addr)
-	err = http.ListenAndServe(addr, mel)
+	srv := &http.Server{Addr: addr, Handler: mel}
+	err = mel.serve(srv, srv.ListenAndServe)
 	debugPrintError(err)
 
 	return
 }
 
 // RunTLS attaches `mel` to a http.Server and starts listening and serving HTTPS (secure) requests.
-// Note: this method will block the calling goroutine indefinitely unless an error happens.
+// Note: this method will block the calling goroutine until Shutdown is called or an error happens.
 func (mel *Mel) RunTLS(addr string, certFile string, keyFile string) (err error) {
 	debugPrint("Listening and serving HTTPS on %s\n", addr)
-	err = http.ListenAndServeTLS(addr, certFile, keyFile, mel)
+	srv := &http.Server{Addr: addr, Handler: mel}
+	err = mel.serve(srv, func() error {
+		return srv.ListenAndServeTLS(certFile, keyFile)
+	})
 	debugPrintError(err)
 
 	return
@@ -114,7 +169,7 @@ func (mel *Mel) RunTLS(addr string, certFile string, keyFile string) (err error)
 
 // RunUnix attaches `mel` to a http.Server and starts listening and serving HTTP requests
 // through the specified unix socket (ie. a file).
-// Note: this method will block the calling goroutine indefinitely unless an error happens.
+// Note: this method will block the calling goroutine until Shutdown is called or an error happens.
 func (mel *Mel) RunUnix(file string) (err error) {
 	debugPrint("Listening and serving HTTP on unix:/%s", file)
 	defer func() { debugPrintError(err) }()
@@ -125,29 +180,69 @@ func (mel *Mel) RunUnix(file string) (err error) {
 		return
 	}
 	defer listener.Close()
-	err = http.Serve(listener, mel)
+	err = mel.Serve(listener)
 
 	return
 }
 
 // ServerHTTP implements the http.Handler interface.
 func (mel *Mel) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	mel.serveHTTP(w, req, nil)
+}
+
+// serveHTTP serves the request with the params of the mount point, if the app is mounted.
+func (mel *Mel) serveHTTP(w http.ResponseWriter, req *http.Request, params Params) {
+	atomic.AddInt64(&mel.active, 1)
+	defer atomic.AddInt64(&mel.active, -1)
+
 	c := mel.pool.Get()
 	c.reset(w, req)
+	c.Params = append(c.Params[:0], params...)
+	// the context goes back to the pool even if a handler panics without Recovery
+	defer mel.pool.Put(c)
 
 	mel.handle(c)
-
-	mel.pool.Put(c)
 }
 
 func (mel *Mel) handle(ctx *Context) {
 	httpMethod := ctx.Request.Method
 	path := ctx.Request.URL.Path
-
-	route, params, tsr := mel.Router.Match(httpMethod, path)
+	if mel.UseRawPath {
+		path = rawMatchPath(ctx.Request.URL)
+	}
+	router, hostParams := mel.Router.forHost(ctx.Request.Host)
+	// the params of the mount point, see RoutesGroup.Mount, and of the host,
+	// followed by the params of the route in the buffer of the context
+	inherited := append(ctx.Params, hostParams...)
+
+	route, params, tsr := router.match(httpMethod, path, inherited)
+	if route == nil && httpMethod == "HEAD" && mel.ImplicitHead {
+		var getTsr bool
+		route, params, getTsr = router.match("GET", path, inherited)
+		tsr = tsr || getTsr
+		if route != nil {
+			w := ctx.Writer
+			hw := &headWriter{ResponseWriter: w}
+			ctx.Writer = hw
+			defer func() { ctx.Writer = w }()
+			route.execute(ctx)
+			ctx.route = route
+			ctx.Params = params
+			if mel.UseRawPath {
+				unescapeParams(params[len(inherited):])
+			}
+			ctx.Next()
+			hw.writeHeader()
+			return
+		}
+	}
 	if route != nil {
 		route.execute(ctx)
+		ctx.route = route
 		ctx.Params = params
+		if mel.UseRawPath {
+			unescapeParams(params[len(inherited):])
+		}
 		ctx.Next()
 		return
 	} else if httpMethod != "CONNECT" && path != "/" {
@@ -155,21 +250,31 @@ func (mel *Mel) handle(ctx *Context) {
 			redirectTrailingSlash(ctx)
 			return
 		}
-		if mel.RedirectFixedPath && redirectFixedPath(ctx, mel.Router, mel.RedirectTrailingSlash) {
+		if (mel.RedirectFixedPath || mel.RedirectCaseInsensitive) && mel.redirectFixedPath(ctx, router) {
 			return
 		}
 	}
 
-	if mel.HandleMethodNotAllowed {
-		for method := range mel.Router.trees {
-			if method != httpMethod {
-				route, _, _ := mel.Router.Match(method, path)
-				if route != nil {
-					ctx.handlers = mel.allNoMethod
-					serveError(ctx, 405, default405Body)
-					return
-				}
+	if httpMethod == "OPTIONS" && mel.HandleOPTIONS {
+		if allow := router.allowed(path, true); len(allow) > 0 {
+			// the global middlewares still run, e.g. to answer CORS preflight requests
+			ctx.Writer.Header().Set("Allow", allow)
+			ctx.handlers = mel.Handlers
+			ctx.Writer.Status(http.StatusNoContent)
+			ctx.Next()
+			if !ctx.Writer.Written() {
+				ctx.Writer.WriteHeader(ctx.Writer.Status())
 			}
+			return
+		}
+	}
+
+	if mel.HandleMethodNotAllowed {
+		if allow := router.allowed(path, mel.HandleOPTIONS); len(allow) > 0 {
+			ctx.Writer.Header().Set("Allow", allow)
+			ctx.handlers = mel.allNoMethod
+			serveError(ctx, 405, default405Body)
+			return
 		}
 	}
 
@@ -178,6 +283,8 @@ func (mel *Mel) handle(ctx *Context) {
 }
 
 func serveError(c *Context, code int, defaultMessage []byte) {
+	// set the status first, so that middlewares see it after calling c.Next()
+	c.Writer.Status(code)
 	c.Next()
 
 	if !c.Writer.Written() {
@@ -197,35 +304,116 @@ func redirectTrailingSlash(c *Context) {
 
 	check(len(path) > 1 && path[len(path)-1] == '/', "Path has no trailing slash")
 	req.URL.Path = path[:len(path)-1]
+	if rawPath := req.URL.RawPath; len(rawPath) > 1 && rawPath[len(rawPath)-1] == '/' {
+		// keep the escaping of the path, e.g. "%2F"
+		req.URL.RawPath = rawPath[:len(rawPath)-1]
+	}
 	debugPrint("redirecting request %d: %s --> %s", code, path, req.URL.String())
 	http.Redirect(c.Writer, req, req.URL.String(), code)
 }
 
-func redirectFixedPath(ctx *Context, router *Router, trailingSlash bool) bool {
+// redirectFixedPath redirects the request to the path of the route which matches its cleaned path
+// if RedirectFixedPath is set, ignoring the case if RedirectCaseInsensitive is set,
+// and without the trailing slash if RedirectTrailingSlash is set.
+func (mel *Mel) redirectFixedPath(ctx *Context, router *Router) bool {
 	req := ctx.Request
 	httpMethod := req.Method
 	path := req.URL.Path
+	if mel.UseRawPath {
+		path = rawMatchPath(req.URL)
+	}
 
-	fixedPath := cleanPath(path)
-	route, _, tsr := router.Match(httpMethod, fixedPath)
-	if route == nil {
-		if !(tsr && trailingSlash) {
-			return false
-		}
-
-		fixedPath = fixedPath[:len(fixedPath)-1]
+	fixedPath := path
+	if mel.RedirectFixedPath {
+		fixedPath = cleanPath(fixedPath)
+	}
+	fixedPath, found := router.fixPath(httpMethod, fixedPath, mel.RedirectCaseInsensitive)
+	if !found && mel.RedirectTrailingSlash && len(fixedPath) > 1 && fixedPath[len(fixedPath)-1] == '/' {
+		fixedPath, found = router.fixPath(httpMethod, fixedPath[:len(fixedPath)-1], mel.RedirectCaseInsensitive)
+	}
+	if !found || fixedPath == path {
+		return false
 	}
 
 	code := 301 // Permanent redirect, request with GET method
 	if req.Method != "GET" {
 		code = 307
 	}
-	req.URL.Path = string(fixedPath)
+	if mel.UseRawPath {
+		unescaped, err := url.PathUnescape(fixedPath)
+		if err != nil {
+			return false
+		}
+		req.URL.Path, req.URL.RawPath = unescaped, escapeRawMatchPath(fixedPath)
+	} else {
+		req.URL.Path = fixedPath
+	}
 	debugPrint("redirecting request %d: %s --> %s", code, path, req.URL.String())
 	http.Redirect(ctx.Writer, req, req.URL.String(), code)
 	return true
 }
 
+// rawMatchPath returns the path matched when UseRawPath is set: the escaped path of the URL, decoded
+// but the encoded slashes and percent signs, so that the static parts of the patterns match
+// as registered, e.g. "/docs/a b", and that the params may contain encoded slashes, e.g. "a%2Fb".
+func rawMatchPath(u *url.URL) string {
+	path := u.EscapedPath()
+	if strings.IndexByte(path, '%') < 0 {
+		return path
+	}
+	buf := make([]byte, 0, len(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] == '%' && i+2 < len(path) && isHex(path[i+1]) && isHex(path[i+2]) {
+			if b := unhex(path[i+1])<<4 | unhex(path[i+2]); b != '/' && b != '%' {
+				buf = append(buf, b)
+				i += 2
+				continue
+			}
+		}
+		buf = append(buf, path[i])
+	}
+	return string(buf)
+}
+
+// escapeRawMatchPath escapes a path returned by rawMatchPath again, keeping its encoded slashes and percent signs.
+func escapeRawMatchPath(path string) string {
+	parts := strings.Split(path, "%")
+	for i, part := range parts {
+		if i > 0 && len(part) >= 2 {
+			parts[i] = part[:2] + (&url.URL{Path: part[2:]}).EscapedPath()
+		} else {
+			parts[i] = (&url.URL{Path: part}).EscapedPath()
+		}
+	}
+	return strings.Join(parts, "%")
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c <= '9':
+		return c - '0'
+	case c >= 'a':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// unescapeParams decodes the values of the params matched in the escaped path, see Mel.UseRawPath.
+func unescapeParams(params Params) {
+	for i := range params {
+		if strings.IndexByte(params[i].Value, '%') > -1 {
+			if value, err := url.PathUnescape(params[i].Value); err == nil {
+				params[i].Value = value
+			}
+		}
+	}
+}
+
 // SetVar stores a new key/value pair exclusivelly for this app.
 // It also lazy initializes mel.vars if it was not used previously.
 func (mel *Mel) SetVar(key string, value interface{}) {
@@ -13,6 +13,9 @@ import (
 	"fmt"
 	"bufio"
 	"runtime"
+	"context"
+	"errors"
+	"io"
 )
 
 func TestCreateApp(t *testing.T) {
@@ -26,22 +29,25 @@ func TestAddRoute(t *testing.T) {
 	router := NewRouter()
 	router.Register("GET", "/", func(*Context) {})
 
-	assert.Len(t, router.trees, len(Methods))
-	assert.NotNil(t, router.trees["GET"].children)
-	assert.Nil(t, router.trees["POST"].children)
-	assert.Len(t, router.trees["GET"].children, 1)
-	assert.Len(t, router.trees["POST"].children, 0)
+	assert.Len(t, router.trees(), len(Methods))
+	assert.NotNil(t, router.trees()["GET"].children())
+	assert.Nil(t, router.trees()["POST"].children())
+	assert.Len(t, router.trees()["GET"].children(), 1)
+	assert.Len(t, router.trees()["POST"].children(), 0)
 
 	router.Register("POST", "/", func(*Context) {})
 
-	assert.NotNil(t, router.trees["GET"].children)
-	assert.NotNil(t, router.trees["POST"].children)
-	assert.Len(t, router.trees["GET"].children, 1)
-	assert.Len(t, router.trees["POST"].children, 1)
+	assert.NotNil(t, router.trees()["GET"].children())
+	assert.NotNil(t, router.trees()["POST"].children())
+	assert.Len(t, router.trees()["GET"].children(), 1)
+	assert.Len(t, router.trees()["POST"].children(), 1)
 
+	// the static paths are compressed, "post" is a child of "/"
 	router.Register("POST", "/post", func(*Context) {})
-	assert.Len(t, router.trees["GET"].children, 1)
-	assert.Len(t, router.trees["POST"].children, 2)
+	assert.Len(t, router.trees()["GET"].children(), 1)
+	assert.Len(t, router.trees()["POST"].children(), 1)
+	assert.Len(t, router.trees()["POST"].children()[0].children(), 1)
+	assert.Equal(t, "post", router.trees()["POST"].children()[0].children()[0].segment)
 }
 
 func TestAddRouteFails(t *testing.T) {
@@ -51,7 +57,12 @@ func TestAddRouteFails(t *testing.T) {
 	assert.Panics(t, func() { router.Register("GET", "/", []Handler{}) })
 
 	router.Register("POST", "/post", func(*Context) {})
-	assert.NotPanics(t, func() { router.Register("POST", "/post", func(*Context) {}) }) // TODO: reasonable?
+	// a duplicate overrides the route, unless in strict mode
+	assert.NotPanics(t, func() { router.Register("POST", "/post", func(*Context) {}) })
+	assert.Len(t, router.Conflicts(), 1)
+
+	router.ConflictMode = ConflictStrict
+	assert.Panics(t, func() { router.Register("POST", "/post", func(*Context) {}) })
 }
 
 func compareFunc(t *testing.T, a, b interface{}) {
@@ -293,3 +304,337 @@ func TestWithHttptestWithSpecifiedPort(t *testing.T) {
 
 	testRequest(t, "http://localhost:8033/example")
 }
+
+func TestShutdownWaitsForActiveRequests(t *testing.T) {
+	router := New()
+	var started, shutdown bool
+	router.OnStart(func() error { started = true; return nil })
+	router.OnShutdown(func() { shutdown = true })
+
+	inHandler := make(chan struct{})
+	router.Get("/slow", func(c *Context) {
+		close(inHandler)
+		time.Sleep(50 * time.Millisecond)
+		c.Text(http.StatusOK, "it worked")
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	served := make(chan error)
+	go func() { served <- router.Serve(l) }()
+
+	done := make(chan struct{})
+	go func() {
+		testRequest(t, "http://" + l.Addr().String() + "/slow")
+		close(done)
+	}()
+
+	<-inHandler
+	assert.True(t, started)
+	assert.NoError(t, router.Shutdown(context.Background()))
+	assert.True(t, shutdown)
+	assert.NoError(t, <-served)
+	<-done
+
+	assert.True(t, router.ShuttingDown())
+	assert.Equal(t, http.ErrServerClosed, router.Run(":5151"))
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	router := New()
+	release := make(chan struct{})
+	router.Get("/block", func(c *Context) { <-release })
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+	defer close(release)
+
+	go http.Get(ts.URL + "/block")
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20 * time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, router.Shutdown(ctx))
+}
+
+func TestShutdownHooksOnce(t *testing.T) {
+	router := New()
+	var calls int
+	router.OnShutdown(func() { calls++ })
+
+	assert.NoError(t, router.Shutdown(context.Background()))
+	assert.NoError(t, router.Shutdown(context.Background()))
+	assert.Equal(t, 1, calls)
+}
+
+func TestShutdownEndsStreams(t *testing.T) {
+	router := New()
+	router.Get("/stream", func(c *Context) {
+		c.Stream(func(w io.Writer) bool {
+			c.SSE("tick", "ok")
+			time.Sleep(5 * time.Millisecond)
+			return true
+		})
+	})
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/stream")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, router.Shutdown(ctx))
+
+	c, _ := CreateTestContext()
+	c.Mel = router
+	assert.Equal(t, http.ErrServerClosed, c.SSE("tick", "ok"))
+}
+
+func TestStartHookError(t *testing.T) {
+	router := New()
+	router.OnStart(func() error { return errors.New("not ready") })
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	assert.EqualError(t, router.Serve(l), "not ready")
+}
+
+func TestMethodNotAllowedAllowHeader(t *testing.T) {
+	router := New()
+	router.HandleMethodNotAllowed = true
+	router.Get("/users/:id", func(c *Context) {})
+	router.Delete("/users/:id", func(c *Context) {})
+	router.Post("/users", func(c *Context) {})
+
+	w := performRequest(router, "PUT", "/users/42")
+	assert.Equal(t, 405, w.Code)
+	assert.Equal(t, "DELETE, GET, HEAD, OPTIONS", w.HeaderMap.Get("Allow"))
+	assert.Equal(t, string(default405Body), w.Body.String())
+
+	router.HandleOPTIONS = false
+	w = performRequest(router, "PUT", "/users/42")
+	assert.Equal(t, "DELETE, GET, HEAD", w.HeaderMap.Get("Allow"))
+
+	// NoMethod handlers can override the response, and see the Allow header
+	router.NoMethod(func(c *Context) {
+		c.Header("Allow", "GET")
+		c.Text(405, "use %s", c.Writer.Header().Get("Allow"))
+	})
+	w = performRequest(router, "PUT", "/users/42")
+	assert.Equal(t, 405, w.Code)
+	assert.Equal(t, "use GET", w.Body.String())
+
+	w = performRequest(router, "PUT", "/unknown")
+	assert.Equal(t, 404, w.Code)
+	assert.Empty(t, w.HeaderMap.Get("Allow"))
+}
+
+func TestAutomaticOptions(t *testing.T) {
+	router := New()
+	var middleware bool
+	router.Use(func(c *Context) {
+		middleware = true
+		c.Header("Access-Control-Allow-Origin", "*")
+		c.Next()
+	})
+	router.Get("/users/:id", func(c *Context) {})
+	router.Put("/users/:id", func(c *Context) {})
+	router.Post("/posts", func(c *Context) {})
+
+	w := performRequest(router, "OPTIONS", "/users/42")
+	assert.Equal(t, 204, w.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS, PUT", w.HeaderMap.Get("Allow"))
+	assert.Equal(t, "*", w.HeaderMap.Get("Access-Control-Allow-Origin"))
+	assert.True(t, middleware)
+	assert.Empty(t, w.Body.String())
+
+	w = performRequest(router, "OPTIONS", "*")
+	assert.Equal(t, 204, w.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS, POST, PUT", w.HeaderMap.Get("Allow"))
+
+	w = performRequest(router, "OPTIONS", "/unknown")
+	assert.Equal(t, 404, w.Code)
+
+	// an OPTIONS route overrides the automatic response
+	router.Options("/users/:id", func(c *Context) { c.Text(200, "custom") })
+	w = performRequest(router, "OPTIONS", "/users/42")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "custom", w.Body.String())
+
+	router.HandleOPTIONS = false
+	w = performRequest(router, "OPTIONS", "/posts")
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestImplicitHead(t *testing.T) {
+	router := New()
+	var size int
+	router.Use(func(c *Context) {
+		c.Next()
+		size = c.Writer.Size()
+	})
+	router.Get("/users/:id", func(c *Context) {
+		c.Header("X-User", c.Param("id"))
+		c.Text(201, "user %s", c.Param("id"))
+	})
+
+	w := performRequest(router, "HEAD", "/users/42")
+	assert.Equal(t, 201, w.Code)
+	assert.Equal(t, "42", w.HeaderMap.Get("X-User"))
+	assert.Equal(t, "text/plain; charset=utf-8", w.HeaderMap.Get("Content-Type"))
+	assert.Equal(t, "7", w.HeaderMap.Get("Content-Length"))
+	assert.Empty(t, w.Body.String())
+	// the middlewares see the size of the discarded body
+	assert.Equal(t, 7, size)
+
+	// the writer is restored for the next request of the pooled context
+	w = performRequest(router, "GET", "/users/42")
+	assert.Equal(t, "user 42", w.Body.String())
+
+	router.HandleMethodNotAllowed = true
+	w = performRequest(router, "POST", "/users/42")
+	assert.Equal(t, "GET, HEAD, OPTIONS", w.HeaderMap.Get("Allow"))
+
+	// an explicit HEAD route is preferred
+	router.Head("/users/:id", func(c *Context) { c.Writer.WriteHeader(204) })
+	w = performRequest(router, "HEAD", "/users/42")
+	assert.Equal(t, 204, w.Code)
+	assert.Empty(t, w.HeaderMap.Get("X-User"))
+}
+
+func TestImplicitHeadDisabled(t *testing.T) {
+	router := New()
+	router.ImplicitHead = false
+	router.Get("/users/:id", func(c *Context) { c.Text(200, "user") })
+
+	w := performRequest(router, "HEAD", "/users/42")
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestParamDefaults(t *testing.T) {
+	router := New()
+	router.Get("/posts(/:page)?", func(c *Context) {
+		c.Text(200, "page %d of %s", c.ParamInt("page"), c.Param("sort", "date"))
+	}).Default("page", "1")
+
+	w := performRequest(router, "GET", "/posts")
+	assert.Equal(t, "page 1 of date", w.Body.String())
+
+	w = performRequest(router, "GET", "/posts/3")
+	assert.Equal(t, "page 3 of date", w.Body.String())
+}
+
+func TestOptionalBracketedParam(t *testing.T) {
+	router := New()
+	router.Get("/posts/(:page[0-9]+)?", func(c *Context) {
+		c.Text(200, "page %s", c.Param("page", "1"))
+	})
+
+	w := performRequest(router, "GET", "/posts")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "page 1", w.Body.String())
+
+	w = performRequest(router, "GET", "/posts/2")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "page 2", w.Body.String())
+
+	w = performRequest(router, "GET", "/posts/")
+	assert.Equal(t, 301, w.Code)
+	assert.Equal(t, "/posts", w.Header().Get("Location"))
+}
+
+func TestUseRawPath(t *testing.T) {
+	router := New()
+	router.Get("/files/:name", func(c *Context) { c.Text(200, "file %s", c.Param("name")) })
+	router.Get("/files/:name/meta", func(c *Context) { c.Text(200, "meta %s", c.Param("name")) })
+	router.Get("/docs/a b", func(c *Context) { c.Text(200, "doc") })
+	router.Get("/café/:dish", func(c *Context) { c.Text(200, "%s", c.Param("dish")) })
+
+	w := performRequest(router, "GET", "/files/a%2Fb")
+	assert.Equal(t, 404, w.Code)
+
+	router.UseRawPath = true
+	w = performRequest(router, "GET", "/files/a%2Fb")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "file a/b", w.Body.String())
+
+	w = performRequest(router, "GET", "/files/a%2Fb%20c/meta")
+	assert.Equal(t, "meta a/b c", w.Body.String())
+
+	w = performRequest(router, "GET", "/files/readme")
+	assert.Equal(t, "file readme", w.Body.String())
+
+	// the static parts of the patterns match unescaped
+	w = performRequest(router, "GET", "/docs/a%20b")
+	assert.Equal(t, "doc", w.Body.String())
+
+	w = performRequest(router, "GET", "/caf%C3%A9/cr%C3%AApe%2Fsucr%C3%A9e")
+	assert.Equal(t, "crêpe/sucrée", w.Body.String())
+
+	w = performRequest(router, "GET", "/café/tarte")
+	assert.Equal(t, "tarte", w.Body.String())
+
+	w = performRequest(router, "GET", "/files/100%25%2F")
+	assert.Equal(t, "file 100%/", w.Body.String())
+
+	// the trailing slash redirect keeps the escaping of the path
+	w = performRequest(router, "GET", "/files/a%2Fb/")
+	assert.Equal(t, 301, w.Code)
+	assert.Equal(t, "/files/a%2Fb", w.Header().Get("Location"))
+
+	router.RedirectFixedPath = true
+	w = performRequest(router, "GET", "/docs/../docs/a%20b")
+	assert.Equal(t, 301, w.Code)
+	assert.Equal(t, "/docs/a%20b", w.Header().Get("Location"))
+	w = performRequest(router, "GET", "/files//a%2Fb")
+	assert.Equal(t, "/files/a%2Fb", w.Header().Get("Location"))
+}
+
+func TestRedirectCaseInsensitive(t *testing.T) {
+	router := New()
+	router.Get("/users/:name/Posts", func(c *Context) { c.Text(200, "%s", c.Param("name")) })
+	router.Post("/users/new", func(c *Context) {})
+	router.Get("/files/*path", func(c *Context) {})
+	router.Get("/files/static/About", func(c *Context) {})
+
+	w := performRequest(router, "GET", "/USERS/Tom/posts")
+	assert.Equal(t, 404, w.Code)
+
+	router.RedirectCaseInsensitive = true
+	w = performRequest(router, "GET", "/USERS/Tom/posts")
+	assert.Equal(t, 301, w.Code)
+	assert.Equal(t, "/users/Tom/Posts", w.Header().Get("Location"))
+
+	w = performRequest(router, "GET", "/Users/Tom/POSTS/")
+	assert.Equal(t, 301, w.Code)
+	assert.Equal(t, "/users/Tom/Posts", w.Header().Get("Location"))
+
+	w = performRequest(router, "POST", "/Users/NEW")
+	assert.Equal(t, 307, w.Code)
+	assert.Equal(t, "/users/new", w.Header().Get("Location"))
+
+	w = performRequest(router, "GET", "/Files/Static/about")
+	assert.Equal(t, 301, w.Code)
+	assert.Equal(t, "/files/static/About", w.Header().Get("Location"))
+
+	w = performRequest(router, "GET", "/FILES/Static/Other")
+	assert.Equal(t, 301, w.Code)
+	assert.Equal(t, "/files/Static/Other", w.Header().Get("Location"))
+
+	// the exact path is still matched first
+	w = performRequest(router, "GET", "/users/Tom/Posts")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "Tom", w.Body.String())
+
+	w = performRequest(router, "GET", "/USERS//Tom/../Tom/posts")
+	assert.Equal(t, 404, w.Code)
+
+	router.RedirectFixedPath = true
+	w = performRequest(router, "GET", "/USERS//Tom/../Tom/posts")
+	assert.Equal(t, 301, w.Code)
+	assert.Equal(t, "/users/Tom/Posts", w.Header().Get("Location"))
+}
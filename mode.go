@@ -0,0 +1,55 @@
+package mel
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// EnvMelMode is the name of the environment variable used to pick the initial mode.
+const EnvMelMode = "MEL_MODE"
+
+const (
+	DebugMode   = "debug"
+	ReleaseMode = "release"
+	TestMode    = "test"
+)
+
+const (
+	debugCode int32 = iota
+	releaseCode
+	testCode
+)
+
+var melMode = debugCode
+
+func init() {
+	SetMode(os.Getenv(EnvMelMode))
+}
+
+// SetMode sets the mode of the framework, which is one of DebugMode, ReleaseMode and TestMode.
+// An empty value selects DebugMode. It panics on an unknown mode.
+// It is safe to call SetMode concurrently, e.g. from parallel tests.
+func SetMode(value string) {
+	switch value {
+	case DebugMode, "":
+		atomic.StoreInt32(&melMode, debugCode)
+	case ReleaseMode:
+		atomic.StoreInt32(&melMode, releaseCode)
+	case TestMode:
+		atomic.StoreInt32(&melMode, testCode)
+	default:
+		panic("mel mode unknown: " + value)
+	}
+}
+
+// Mode returns the current mode of the framework.
+func Mode() string {
+	switch atomic.LoadInt32(&melMode) {
+	case releaseCode:
+		return ReleaseMode
+	case testCode:
+		return TestMode
+	default:
+		return DebugMode
+	}
+}
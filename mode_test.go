@@ -0,0 +1,49 @@
+package mel
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMode(t *testing.T) {
+	defer SetMode(Mode())
+
+	SetMode("")
+	assert.Equal(t, DebugMode, Mode())
+	assert.True(t, IsDebugging())
+
+	SetMode(ReleaseMode)
+	assert.Equal(t, ReleaseMode, Mode())
+	assert.False(t, IsDebugging())
+
+	SetMode(TestMode)
+	assert.Equal(t, TestMode, Mode())
+	assert.False(t, IsDebugging())
+
+	SetMode(DebugMode)
+	assert.Equal(t, DebugMode, Mode())
+	assert.True(t, IsDebugging())
+
+	assert.Panics(t, func() { SetMode("unknown") })
+}
+
+func TestDebugPrintOnlyInDebugMode(t *testing.T) {
+	defer SetMode(Mode())
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	SetMode(ReleaseMode)
+	router := New()
+	router.Get("/users/:id", func(c *Context) {})
+	assert.Empty(t, buf.String())
+
+	SetMode(DebugMode)
+	router.Get("/posts/:id", func(c *Context) {})
+	assert.Contains(t, buf.String(), "/posts/:id")
+	assert.Contains(t, buf.String(), "(1 handlers)")
+}
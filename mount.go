@@ -0,0 +1,81 @@
+package mel
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// mountParam is the wildcard param of the routes registered by Mount.
+const mountParam = "*mountpath"
+
+// Mount serves all the requests under the prefix with handler, e.g. a http.FileServer
+// or another *Mel built independently, for all the HTTP methods.
+// The prefix is stripped from the request path, and the middlewares of the group run
+// before handler. The prefix may contain params, e.g. "/tenants/:tenant/admin",
+// and a mounted *Mel finds them in the Params of its Context, before its own params.
+func (group *RoutesGroup) Mount(prefix string, handler http.Handler) {
+	check(handler != nil, "Mounted handler can not be nil")
+	check(!strings.Contains(prefix, "*"), "Mount prefix can not contain a wildcard param")
+
+	child, _ := handler.(*Mel)
+	target := func(c *Context) {
+		req := mountedRequest(c.Request, c.Param(mountParam))
+		if child == nil {
+			handler.ServeHTTP(c.Writer, req)
+			return
+		}
+
+		var params Params
+		for _, p := range c.Params {
+			if p.Key != mountParam {
+				params = append(params, p)
+			}
+		}
+		child.serveHTTP(c.Writer, req, params)
+	}
+
+	group.Any(prefix, target)
+	group.Any(joinPaths(prefix, "/"+mountParam), target)
+}
+
+// mountedRequest returns a shallow copy of req whose path is the rest of the path after the prefix.
+// The escaped path keeps its encoding, e.g. "%2F", as in a request sent to the mounted handler directly.
+func mountedRequest(req *http.Request, rest string) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	r.URL = new(url.URL)
+	*r.URL = *req.URL
+	r.URL.Path = "/" + rest
+	r.URL.RawPath = ""
+	if escaped, ok := escapedSuffix(req.URL, rest); ok && escaped != r.URL.EscapedPath() {
+		r.URL.RawPath = escaped
+	}
+	return r
+}
+
+// escapedSuffix returns "/" followed by the escaped form of rest, the end of the path of u,
+// as found in the escaped path of u. It returns false if rest does not end the path.
+func escapedSuffix(u *url.URL, rest string) (string, bool) {
+	if !strings.HasSuffix(u.Path, rest) {
+		return "", false
+	}
+	// skip the escaped form of the prefix, the '%' of an escape being decoded into one byte
+	escaped := u.EscapedPath()
+	i, n := 0, len(u.Path)-len(rest)
+	for ; n > 0 && i < len(escaped); n-- {
+		if escaped[i] == '%' {
+			i += 3
+		} else {
+			i++
+		}
+	}
+	if i > len(escaped) {
+		return "", false
+	}
+	suffix := "/" + escaped[i:]
+	if unescaped, err := url.PathUnescape(suffix); err != nil || unescaped != "/"+rest {
+		return "", false
+	}
+	return suffix, true
+}
@@ -0,0 +1,82 @@
+package mel
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMountHandler(t *testing.T) {
+	router := New()
+	var middleware bool
+	router.Use(func(c *Context) {
+		middleware = true
+		c.Next()
+	})
+	router.Mount("/legacy", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(202)
+		w.Write([]byte(req.Method + " " + req.URL.Path))
+	}))
+
+	w := performRequest(router, "GET", "/legacy/users/42?page=2")
+	assert.Equal(t, 202, w.Code)
+	assert.Equal(t, "GET /users/42", w.Body.String())
+	assert.True(t, middleware)
+
+	w = performRequest(router, "DELETE", "/legacy")
+	assert.Equal(t, "DELETE /", w.Body.String())
+
+	w = performRequest(router, "GET", "/legacyusers")
+	assert.Equal(t, 404, w.Code)
+
+	// the escaped path keeps its encoding
+	router.Mount("/raw/:id", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.URL.Path + " " + req.URL.EscapedPath()))
+	}))
+	w = performRequest(router, "GET", "/raw/a%20b/files/a%2Fb%20c")
+	assert.Equal(t, "/files/a/b c /files/a%2Fb%20c", w.Body.String())
+}
+
+func TestMountMel(t *testing.T) {
+	admin := New()
+	var adminMiddleware bool
+	admin.Use(func(c *Context) {
+		adminMiddleware = true
+		c.Next()
+	})
+	admin.Get("/", func(c *Context) {
+		c.Text(200, "dashboard of %s", c.Param("tenant"))
+	})
+	admin.Get("/users/:id", func(c *Context) {
+		c.JSON(200, c.Params)
+	})
+
+	router := New()
+	var middleware bool
+	router.Use(func(c *Context) {
+		middleware = true
+		c.Next()
+	})
+	router.Group("/tenants").Mount("/:tenant/admin", admin)
+
+	w := performRequest(router, "GET", "/tenants/acme/admin")
+	assert.Equal(t, "dashboard of acme", w.Body.String())
+	assert.True(t, middleware)
+	assert.True(t, adminMiddleware)
+
+	w = performRequest(router, "GET", "/tenants/acme/admin/users/42")
+	assert.Equal(t, `[{"Key":":tenant","Value":"acme"},{"Key":":id","Value":"42"}]`+"\n", w.Body.String())
+
+	w = performRequest(router, "GET", "/tenants/acme/admin/unknown")
+	assert.Equal(t, 404, w.Code)
+
+	admin.UseRawPath = true
+	admin.Get("/files/:name", func(c *Context) {
+		c.Text(200, "%s", c.Param("name"))
+	})
+	w = performRequest(router, "GET", "/tenants/acme/admin/files/a%2Fb")
+	assert.Equal(t, "a/b", w.Body.String())
+
+	assert.Panics(t, func() { router.Mount("/static/*filepath", admin) })
+}
@@ -0,0 +1,178 @@
+package mel
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ridewindx/mel/binding"
+)
+
+// acceptRange is a media range of the Accept header, e.g. "text/*;q=0.8".
+type acceptRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// parseAccept parses the media ranges of an Accept header, the most preferred first.
+// The ranges with an invalid quality are ignored.
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, s := range strings.Split(header, ",") {
+		params := strings.Split(s, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if len(mediaType) == 0 {
+			continue
+		}
+		r := acceptRange{typ: mediaType, subtype: "*", q: 1}
+		if i := strings.IndexByte(mediaType, '/'); i > -1 {
+			r.typ, r.subtype = mediaType[:i], mediaType[i+1:]
+		}
+
+		valid := true
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if len(param) < 2 || (param[0] != 'q' && param[0] != 'Q') || param[1] != '=' {
+				continue
+			}
+			q, err := strconv.ParseFloat(param[2:], 64)
+			if err != nil || q < 0 || q > 1 {
+				valid = false
+				break
+			}
+			r.q = q
+		}
+		if valid {
+			ranges = append(ranges, r)
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	return ranges
+}
+
+// negotiateContentType returns the offered content type best accepted by the Accept header,
+// or an empty string if none is acceptable. The quality of an offer is the one of
+// the most specific media range which matches it, and the first offer wins a tie.
+// Every offer is accepted if the header is empty.
+func negotiateContentType(header string, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if len(strings.TrimSpace(header)) == 0 {
+		return offers[0]
+	}
+
+	ranges := parseAccept(header)
+	var best string
+	var bestQ float64
+	for _, offer := range offers {
+		typ, subtype := offer, ""
+		if i := strings.IndexByte(offer, '/'); i > -1 {
+			typ, subtype = offer[:i], offer[i+1:]
+		}
+		if i := strings.IndexByte(subtype, ';'); i > -1 {
+			subtype = strings.TrimSpace(subtype[:i])
+		}
+		typ, subtype = strings.ToLower(typ), strings.ToLower(subtype)
+
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			var s int
+			switch {
+			case r.typ == typ && r.subtype == subtype:
+				s = 2
+			case r.typ == typ && r.subtype == "*":
+				s = 1
+			case r.typ == "*" && r.subtype == "*":
+				s = 0
+			default:
+				continue
+			}
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// Negotiation holds the formats offered by Context.Negotiate, and the data to render in them.
+type Negotiation struct {
+	Offered  []string    // the offered media types, the preferred first, e.g. binding.MIMEJSON
+	Fallback string      // the format rendered if none of the offered ones is acceptable, instead of 406
+	Data     interface{} // the data of the formats which have no data of their own
+
+	JSONData interface{}
+	XMLData  interface{}
+	YAMLData interface{}
+	TextData interface{}
+	HTMLData interface{}
+	HTMLName string // the name of the template of the app which renders HTML, the template itself if empty
+}
+
+var errNotAcceptable = errors.New("None of the offered formats is acceptable")
+
+// NegotiateFormat returns the offered media type best accepted by the Accept header of the request,
+// according to the qualities and the wildcards of its media ranges, or an empty string if none is acceptable.
+// The first offer wins a tie, and is returned if the request has no Accept header.
+func (c *Context) NegotiateFormat(offered ...string) string {
+	return negotiateContentType(c.requestHeader("Accept"), offered...)
+}
+
+// Negotiate renders the data with the status in the offered format best accepted by the request,
+// see NegotiateFormat, and adds Accept to the Vary header of the response.
+// JSON, XML, YAML, HTML and plain text are rendered by render.Renderer, and the data of
+// the other formats, e.g. "image/png", is written as is if it is a []byte.
+// If none of the formats is acceptable, it renders the fallback format if any, or else
+// it aborts with 406 Not Acceptable and returns an error.
+func (c *Context) Negotiate(status int, n Negotiation) error {
+	c.Writer.Header().Add("Vary", "Accept")
+
+	format := c.NegotiateFormat(n.Offered...)
+	if len(format) == 0 {
+		if len(n.Fallback) == 0 {
+			c.AbortWithError(http.StatusNotAcceptable, errNotAcceptable)
+			return errNotAcceptable
+		}
+		format = n.Fallback
+	}
+
+	mediaType := strings.ToLower(format)
+	if i := strings.IndexByte(mediaType, ';'); i > -1 {
+		mediaType = strings.TrimSpace(mediaType[:i])
+	}
+	switch mediaType {
+	case binding.MIMEJSON:
+		return c.JSON(status, n.data(n.JSONData))
+	case binding.MIMEXML, binding.MIMEXMLText:
+		return c.XML(status, n.data(n.XMLData))
+	case binding.MIMEYAML:
+		return c.YAML(status, n.data(n.YAMLData))
+	case binding.MIMEPlain:
+		return c.Text(status, "%v", n.data(n.TextData))
+	case binding.MIMEHTML:
+		return c.HTML(status, n.HTMLName, n.data(n.HTMLData))
+	}
+
+	if data, ok := n.Data.([]byte); ok {
+		return c.Data(status, format, data)
+	}
+	return fmt.Errorf("The data of %s must be a []byte", format)
+}
+
+func (n *Negotiation) data(specific interface{}) interface{} {
+	if specific != nil {
+		return specific
+	}
+	return n.Data
+}
@@ -0,0 +1,117 @@
+package mel
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ridewindx/mel/binding"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{"application/json", "application/xml", "text/html"}
+
+	assert.Equal(t, "application/json", negotiateContentType("", offers...))
+	assert.Equal(t, "application/json", negotiateContentType("*/*", offers...))
+	assert.Equal(t, "application/xml", negotiateContentType("application/xml", offers...))
+	assert.Equal(t, "text/html", negotiateContentType("text/*", offers...))
+	assert.Equal(t, "application/xml", negotiateContentType("application/json;q=0.5, application/xml", offers...))
+	assert.Equal(t, "application/json", negotiateContentType("application/*;q=0.8, application/xml;q=0.2", offers...))
+	assert.Equal(t, "text/html", negotiateContentType("text/html, application/xhtml+xml, */*;q=0.8", offers...))
+	assert.Equal(t, "application/json", negotiateContentType("*/*, text/html;q=0", offers...))
+	assert.Equal(t, "", negotiateContentType("image/png", offers...))
+	assert.Equal(t, "", negotiateContentType("application/json;q=0", offers...))
+	assert.Equal(t, "application/xml", negotiateContentType("application/json;q=2, application/xml", offers...))
+}
+
+func TestContextNegotiate(t *testing.T) {
+	router := New()
+	router.Template = template.Must(template.New("user").Parse(`<p>{{.Name}}</p>`))
+	type user struct {
+		Name string `json:"name" xml:"name" yaml:"name"`
+	}
+	router.Get("/user", func(c *Context) {
+		c.Negotiate(200, Negotiation{
+			Offered:  []string{binding.MIMEJSON, binding.MIMEXML, binding.MIMEYAML, binding.MIMEHTML, binding.MIMEPlain},
+			Data:     user{"tom"},
+			TextData: "tom",
+		})
+	})
+	router.Get("/avatar", func(c *Context) {
+		if err := c.Negotiate(201, Negotiation{
+			Offered: []string{"image/png"},
+			Data:    []byte("png"),
+		}); err != nil {
+			c.Text(500, "%s", err)
+		}
+	})
+
+	negotiate := func(path, accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		if len(accept) > 0 {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	items := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"", "application/json; charset=utf-8", `{"name":"tom"}` + "\n"},
+		{"*/*", "application/json; charset=utf-8", `{"name":"tom"}` + "\n"},
+		{"application/xml", "application/xml; charset=utf-8", `<user><name>tom</name></user>`},
+		{"text/xml;q=0.5, application/x-yaml", "application/x-yaml; charset=utf-8", "name: tom\n"},
+		{"text/html, application/xhtml+xml, */*;q=0.8", "text/html; charset=utf-8", `<p>tom</p>`},
+		{"text/*;q=0.5, text/html;q=0.1", "text/plain; charset=utf-8", "tom"},
+		{"application/*;q=0.2, application/xml;q=0.1", "application/json; charset=utf-8", `{"name":"tom"}` + "\n"},
+	}
+	for _, item := range items {
+		w := negotiate("/user", item.accept)
+		assert.Equal(t, 200, w.Code, item.accept)
+		assert.Equal(t, item.contentType, w.Header().Get("Content-Type"), item.accept)
+		assert.Equal(t, item.body, w.Body.String(), item.accept)
+		assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	}
+
+	w := negotiate("/user", "image/png, application/json;q=0")
+	assert.Equal(t, 406, w.Code)
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+
+	w = negotiate("/avatar", "image/*")
+	assert.Equal(t, 201, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, "png", w.Body.String())
+}
+
+func TestContextNegotiateErrors(t *testing.T) {
+	c, w := CreateTestContext()
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	c.Request.Header.Set("Accept", "text/csv")
+
+	err := c.Negotiate(200, Negotiation{Offered: []string{binding.MIMEJSON}, Data: 1})
+	assert.Equal(t, errNotAcceptable, err)
+	assert.True(t, c.IsAborted())
+	assert.Len(t, c.Errors, 1)
+	assert.Equal(t, 406, w.Code)
+
+	// the fallback format is rendered instead of 406
+	c, w = CreateTestContext()
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	c.Request.Header.Set("Accept", "text/csv")
+	err = c.Negotiate(200, Negotiation{Offered: []string{binding.MIMEXML, binding.MIMEJSON}, Fallback: binding.MIMEJSON, Data: 1})
+	assert.NoError(t, err)
+	assert.False(t, c.IsAborted())
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "1\n", w.Body.String())
+
+	c, _ = CreateTestContext()
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	assert.Error(t, c.Negotiate(200, Negotiation{Offered: []string{"text/csv"}, Data: "a,b"}))
+	assert.Equal(t, "text/csv", c.NegotiateFormat("text/csv", binding.MIMEJSON))
+}
@@ -0,0 +1,61 @@
+package mel
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+var default500Body = []byte("500 internal server error")
+
+// RecoveryReporter is called by the Recovery middleware with the recovered panic value
+// and the stack trace of the goroutine that panicked.
+type RecoveryReporter func(c *Context, err interface{}, stack []byte)
+
+// Recovery returns a middleware that recovers from any panic in the handlers after it.
+// In debug mode, the panic and its stack trace are logged.
+// If the response has not been written yet, it writes a 500. The public errors recorded
+// in Context.Errors are rendered as JSON, otherwise a plain text body is written.
+func Recovery() Handler {
+	return RecoveryWithReporter(nil)
+}
+
+// RecoveryWithReporter returns a Recovery middleware that also passes every recovered panic to reporter,
+// e.g. to send it to an error tracking service.
+func RecoveryWithReporter(reporter RecoveryReporter) Handler {
+	return func(c *Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				// the handler intends to abort the response, let net/http handle it
+				panic(err)
+			}
+
+			stack := debug.Stack()
+			debugPrint("[Recovery] panic recovered: %v\n%s\n", err, stack)
+			if reporter != nil {
+				reporter(c, err, stack)
+			}
+
+			if e, ok := err.(error); ok {
+				c.Error(e)
+			} else {
+				c.Error(fmt.Errorf("%v", err))
+			}
+
+			c.Abort()
+			if c.Writer.Written() {
+				return
+			}
+			if public := c.Errors.ByType(ErrorTypePublic); len(public) > 0 {
+				c.JSON(http.StatusInternalServerError, public)
+			} else {
+				c.Data(http.StatusInternalServerError, "text/plain; charset=utf-8", default500Body)
+			}
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,102 @@
+package mel
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecovery(t *testing.T) {
+	defer SetMode(Mode())
+	SetMode(DebugMode)
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	router := Default()
+	router.Get("/panic", func(c *Context) {
+		c.Header("X-Before", "yes")
+		var params Params
+		params[1].Value = "out of range"
+	})
+
+	w := performRequest(router, "GET", "/panic")
+	assert.Equal(t, 500, w.Code)
+	assert.Equal(t, string(default500Body), w.Body.String())
+	assert.Contains(t, buf.String(), "[Recovery] panic recovered")
+	assert.Contains(t, buf.String(), "index out of range")
+	assert.Contains(t, buf.String(), "recovery_test.go")
+}
+
+func TestRecoveryQuietOutsideDebug(t *testing.T) {
+	defer SetMode(Mode())
+	SetMode(ReleaseMode)
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	router := Default()
+	router.Get("/panic", func() { panic("oops") })
+
+	w := performRequest(router, "GET", "/panic")
+	assert.Equal(t, 500, w.Code)
+	assert.Empty(t, buf.String())
+}
+
+func TestRecoveryRendersPublicErrors(t *testing.T) {
+	router := New()
+	router.Use(Recovery())
+	router.Get("/panic", func(c *Context) {
+		c.Error(errors.New("invalid token")).Type = ErrorTypePublic
+		c.Error(errors.New("hidden"))
+		panic("oops")
+	})
+
+	w := performRequest(router, "GET", "/panic")
+	assert.Equal(t, 500, w.Code)
+	assert.Equal(t, "{\"error\":\"invalid token\"}\n", w.Body.String())
+}
+
+func TestRecoveryWithReporter(t *testing.T) {
+	var reported interface{}
+	var stack []byte
+	var errs Errors
+
+	router := New()
+	router.Use(func(c *Context) {
+		c.Next()
+		errs = c.Errors
+	})
+	router.Use(RecoveryWithReporter(func(c *Context, err interface{}, s []byte) {
+		reported = err
+		stack = s
+	}))
+	router.Get("/panic", func(c *Context) {
+		c.Text(http.StatusAccepted, "partial")
+		panic(errors.New("oops"))
+	})
+
+	w := performRequest(router, "GET", "/panic")
+	assert.EqualError(t, reported.(error), "oops")
+	assert.NotEmpty(t, stack)
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs.Last(), "oops")
+
+	// the response was already written, so it is left as it is
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "partial", w.Body.String())
+}
+
+func TestRecoveryAbortHandler(t *testing.T) {
+	router := Default()
+	router.Get("/abort", func() { panic(http.ErrAbortHandler) })
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		performRequest(router, "GET", "/abort")
+	})
+}
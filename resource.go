@@ -0,0 +1,122 @@
+package mel
+
+import (
+	"reflect"
+	"strings"
+)
+
+// resourceAction maps a method of a resource controller to a route.
+type resourceAction struct {
+	name   string // the name of the controller method
+	method string // the HTTP method
+	path   string // the path relative to the resource path
+}
+
+var resourceActions = []resourceAction{
+	{"Index", "GET", ""},
+	{"New", "GET", "/new"},
+	{"Create", "POST", ""},
+	{"Show", "GET", "/:id"},
+	{"Edit", "GET", "/:id/edit"},
+	{"Update", "PUT", "/:id"},
+	{"Patch", "PATCH", "/:id"},
+	{"Destroy", "DELETE", "/:id"},
+}
+
+// Resource registers the RESTful routes of a resource, handled by the methods of the controller,
+// which must be a pointer to a struct:
+//
+//	GET    /users          Index
+//	GET    /users/new      New
+//	POST   /users          Create
+//	GET    /users/:id      Show
+//	GET    /users/:id/edit Edit
+//	PUT    /users/:id      Update
+//	PATCH  /users/:id      Patch, or else Update
+//	DELETE /users/:id      Destroy
+//
+// The methods which the controller does not have are not registered, and they may have
+// any of the signatures accepted by Register for a function. If the controller has
+// a Before(*Context) or an After(*Context) method, it runs before or after every action,
+// as a middleware after the handlers. The handlers also apply to the nested resources.
+//
+// Resource returns the group of a member of the resource, e.g. "/users/:user_id",
+// so that nested resources can be registered, e.g. "/users/:user_id/posts".
+// Its param is named after the singular of the last segment of the path, with the suffix "_id".
+func (group *RoutesGroup) Resource(relativePath string, controller interface{}, handlers ...Handler) *RoutesGroup {
+	v := reflect.ValueOf(controller)
+	check(v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct,
+		"Resource controller must be a pointer to struct")
+
+	// the middlewares of the controller only apply to its actions, not to the nested resources
+	var middlewares []interface{}
+	if c, ok := controller.(interface {
+		Before(*Context)
+	}); ok {
+		middlewares = append(middlewares, Handler(c.Before))
+	}
+	if c, ok := controller.(interface {
+		After(*Context)
+	}); ok {
+		middlewares = append(middlewares, Handler(func(ctx *Context) {
+			ctx.Next()
+			c.After(ctx)
+		}))
+	}
+
+	resources := group.Group(relativePath, handlers...)
+	var registered bool
+	for _, action := range resourceActions {
+		name := action.name
+		m := v.MethodByName(name)
+		if !m.IsValid() && name == "Patch" {
+			name = "Update"
+			m = v.MethodByName(name)
+		}
+		if !m.IsValid() {
+			continue
+		}
+
+		method := m.Interface()
+		if h := controllerHandler(controller, name); h != nil {
+			method = h
+		}
+		target := append(middlewares[:len(middlewares):len(middlewares)], method)
+		endpoint := resources.handle(action.method, action.path, target)
+		for _, route := range endpoint.routes {
+			route.controller = true
+		}
+		registered = true
+	}
+	check(registered, "Resource controller has no action: "+v.Type().String())
+
+	return resources.Group("/:" + resourceParam(relativePath))
+}
+
+// resourceParam returns the name of the member param of a resource path,
+// e.g. "user_id" for "/users" and "category_id" for "/categories".
+func resourceParam(relativePath string) string {
+	name := strings.Trim(relativePath, "/")
+	if i := strings.LastIndexByte(name, '/'); i > -1 {
+		name = name[i+1:]
+	}
+	check(len(name) > 0 && name[0] != ':' && name[0] != '*' && name[0] != '(',
+		"Resource path must end with a static segment: "+relativePath)
+
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		name = name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "ses"), strings.HasSuffix(name, "xes"):
+		name = name[:len(name)-2]
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss"):
+		name = name[:len(name)-1]
+	}
+
+	bs := []byte(name)
+	for i := range bs {
+		if !isAlphaNum(bs[i]) {
+			bs[i] = '_'
+		}
+	}
+	return string(bs) + "_id"
+}
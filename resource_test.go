@@ -0,0 +1,120 @@
+package mel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type usersController struct {
+	calls []string
+}
+
+func (u *usersController) Before(c *Context) {
+	u.calls = append(u.calls, "before")
+	if c.Request.Header.Get("Authorization") == "" {
+		c.Abort()
+		c.Text(401, "unauthorized")
+	}
+}
+
+func (u *usersController) After(c *Context) {
+	u.calls = append(u.calls, "after")
+}
+
+func (u *usersController) Index(c *Context) {
+	u.calls = append(u.calls, "index")
+	c.Text(200, "users")
+}
+
+func (u *usersController) Show(c *Context) {
+	c.Text(200, "user %s", c.Param("id"))
+}
+
+func (u *usersController) Update(c *Context) {
+	c.Text(200, "update user %s with %s", c.Param("id"), c.Request.Method)
+}
+
+type postsController struct{}
+
+func (p *postsController) Index(c *Context) {
+	c.Text(200, "posts of user %s", c.Param("user_id"))
+}
+
+func (p *postsController) New(c *Context) {
+	c.Text(200, "new post")
+}
+
+func (p *postsController) Show(c *Context) {
+	c.Text(200, "post %s of user %s", c.Param("id"), c.Param("user_id"))
+}
+
+func (p *postsController) Patch(c *Context) {
+	c.Text(200, "patch post %s", c.Param("id"))
+}
+
+func (p *postsController) Destroy(c *Context) {
+	c.Writer.WriteHeader(204)
+}
+
+func performAuthorizedRequest(r *Mel, method, path string) string {
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Body.String()
+}
+
+func TestResource(t *testing.T) {
+	users := &usersController{}
+	router := New()
+	posts := router.Resource("/users", users)
+	assert.Equal(t, "/users/:user_id", posts.BasePath)
+	posts.Resource("/posts", &postsController{})
+
+	assert.Equal(t, "users", performAuthorizedRequest(router, "GET", "/users"))
+	assert.Equal(t, []string{"before", "index", "after"}, users.calls)
+	assert.Equal(t, "user 42", performAuthorizedRequest(router, "GET", "/users/42"))
+	assert.Equal(t, "update user 42 with PUT", performAuthorizedRequest(router, "PUT", "/users/42"))
+	assert.Equal(t, "update user 42 with PATCH", performAuthorizedRequest(router, "PATCH", "/users/42"))
+
+	w := performRequest(router, "GET", "/users")
+	assert.Equal(t, 401, w.Code)
+	w = performRequest(router, "DELETE", "/users/42")
+	assert.Equal(t, 404, w.Code)
+
+	// the middlewares of the users controller do not apply to the posts
+	users.calls = nil
+	w = performRequest(router, "GET", "/users/42/posts")
+	assert.Equal(t, "posts of user 42", w.Body.String())
+	assert.Empty(t, users.calls)
+
+	assert.Equal(t, "new post", performRequest(router, "GET", "/users/42/posts/new").Body.String())
+	assert.Equal(t, "post 7 of user 42", performRequest(router, "GET", "/users/42/posts/7").Body.String())
+	assert.Equal(t, "patch post 7", performRequest(router, "PATCH", "/users/42/posts/7").Body.String())
+	assert.Equal(t, 204, performRequest(router, "DELETE", "/users/42/posts/7").Code)
+	assert.Equal(t, 404, performRequest(router, "PUT", "/users/42/posts/7").Code)
+
+	for _, route := range router.Routes() {
+		assert.Equal(t, "struct", route.Kind)
+		assert.True(t, strings.HasPrefix(route.Path, "/users"))
+	}
+}
+
+func TestResourceInvalid(t *testing.T) {
+	router := New()
+	assert.Panics(t, func() { router.Resource("/users", usersController{}) })
+	assert.Panics(t, func() { router.Resource("/users", &struct{}{}) })
+	assert.Panics(t, func() { router.Resource("/users/:id", &postsController{}) })
+}
+
+func TestResourceParam(t *testing.T) {
+	assert.Equal(t, "user_id", resourceParam("/users"))
+	assert.Equal(t, "category_id", resourceParam("/categories"))
+	assert.Equal(t, "box_id", resourceParam("/boxes"))
+	assert.Equal(t, "address_id", resourceParam("/addresses"))
+	assert.Equal(t, "blog_post_id", resourceParam("/api/blog-posts/"))
+}
@@ -5,6 +5,7 @@ import (
     "net"
     "bufio"
     "io"
+    "strconv"
 )
 
 // ResponseWriter is a wrapper around http.ResponseWriter that
@@ -116,3 +117,57 @@ func (w *responseWriter) Flush() {
         flusher.Flush()
     }
 }
+
+// headWriter answers a HEAD request with a GET route: it discards the body,
+// and counts its size in order to set the Content-Length header.
+type headWriter struct {
+    ResponseWriter
+    size        int
+    wroteHeader bool
+}
+
+func (w *headWriter) WriteHeader(status int) {
+    if w.Written() {
+        return
+    }
+    w.ResponseWriter.Status(status)
+    w.wroteHeader = true
+}
+
+func (w *headWriter) Write(bytes []byte) (int, error) {
+    if !w.Written() {
+        w.WriteHeader(w.Status())
+    }
+    w.size += len(bytes)
+    return len(bytes), nil
+}
+
+func (w *headWriter) WriteString(s string) (int, error) {
+    return w.Write([]byte(s))
+}
+
+// Size returns the size of the discarded body, e.g. for the Logger middleware.
+func (w *headWriter) Size() int {
+    return w.size
+}
+
+func (w *headWriter) Written() bool {
+    return w.wroteHeader || w.ResponseWriter.Written()
+}
+
+func (w *headWriter) Flush() {
+    w.writeHeader()
+    w.ResponseWriter.Flush()
+}
+
+// writeHeader writes the delayed header, with the Content-Length of the discarded body.
+func (w *headWriter) writeHeader() {
+    if w.ResponseWriter.Written() {
+        return
+    }
+    header := w.Header()
+    if w.size > 0 && len(header.Get("Content-Length")) == 0 {
+        header.Set("Content-Length", strconv.Itoa(w.size))
+    }
+    w.ResponseWriter.WriteHeader(w.Status())
+}
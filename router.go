@@ -8,6 +8,8 @@ import (
 	"sort"
 	"strings"
 	"net/http"
+	"sync"
+	"sync/atomic"
 )
 
 var Methods = []string{
@@ -29,112 +31,231 @@ const (
 	FuncRepRoute                     // func (http.ResponseWriter)
 	FuncReqRoute                     // func (*http.Request)
 	FuncCtxRoute                     // func (*mel.Context)
+	FuncTypedRoute                   // func (*mel.Context, *Req) (Resp, error), see newTypedHandler
 )
 
 type Route struct {
 	kind   RouteKind
 	method reflect.Value
 	handlers []Handler
+	chain    []Handler // the handlers followed by the target, built once at registration
+	path   string // the registered pattern
+
+	handlerName string
+	controller  bool // registered with a struct pointer
+	endpoint    *Endpoint
+	defaults    Params // the values of the params missing from the matched path
+	typed       *typedHandler // non-null when kind is FuncTypedRoute
 }
 
-func (r *Route) execute(ctx *Context) {
-	target := func(ctx *Context) {
-		var args []reflect.Value
-		switch r.kind {
-		case FuncRoute:
-		case FuncRepReqRoute:
-			args = []reflect.Value{reflect.ValueOf(ctx.Writer), reflect.ValueOf(ctx.Request)}
-		case FuncRepRoute:
-			args = []reflect.Value{reflect.ValueOf(ctx.Writer)}
-		case FuncReqRoute:
-			args = []reflect.Value{reflect.ValueOf(ctx.Request)}
-		case FuncCtxRoute:
-			args = []reflect.Value{reflect.ValueOf(ctx)}
+// withDefaults appends the defaults of the params missing from params.
+func (r *Route) withDefaults(params Params) Params {
+	if r == nil {
+		return params
+	}
+	for _, d := range r.defaults {
+		if _, err := params.String(d.Key); err != nil {
+			params = append(params, d)
 		}
-		r.method.Call(args)
 	}
-
-	ctx.handlers = append(r.handlers, target)
+	return params
 }
 
-type nodeKind byte
+func (r *Route) execute(ctx *Context) {
+	ctx.handlers = r.chain
+}
 
-const (
-	staticNode nodeKind = iota
-	namedNode
-	anyNode
-	regexNode
+var (
+	funcType       = reflect.TypeOf(func() {})
+	handlerType    = reflect.TypeOf(Handler(nil))
+	repReqFuncType = reflect.TypeOf(func(http.ResponseWriter, *http.Request) {})
+	repFuncType    = reflect.TypeOf(func(http.ResponseWriter) {})
+	reqFuncType    = reflect.TypeOf(func(*http.Request) {})
 )
 
-type node struct {
-	kind     nodeKind
-	segment  string // path segment
-	regexp   *regexp.Regexp // non-null when kind is regexNode
+// build builds the chain of the route, with its target called without reflection
+// when the function has no return values, so that executing it does not allocate.
+// The methods of a struct controller are bound method values, which reflect still calls
+// through a small trampoline, unless they are func(*Context), see controllerHandler.
+func (r *Route) build() {
+	var target Handler
+	fn := r.method
+	switch {
+	case r.kind == FuncTypedRoute:
+		target = func(ctx *Context) {
+			r.typed.call(ctx, fn)
+		}
+	case r.kind == FuncCtxRoute && fn.Type().ConvertibleTo(handlerType):
+		target = fn.Convert(handlerType).Interface().(Handler)
+	case r.kind == FuncRoute && fn.Type().ConvertibleTo(funcType):
+		f := fn.Convert(funcType).Interface().(func())
+		target = func(*Context) {
+			f()
+		}
+	case r.kind == FuncRepReqRoute && fn.Type().ConvertibleTo(repReqFuncType):
+		f := fn.Convert(repReqFuncType).Interface().(func(http.ResponseWriter, *http.Request))
+		target = func(ctx *Context) {
+			f(ctx.Writer, ctx.Request)
+		}
+	case r.kind == FuncRepRoute && fn.Type().ConvertibleTo(repFuncType):
+		f := fn.Convert(repFuncType).Interface().(func(http.ResponseWriter))
+		target = func(ctx *Context) {
+			f(ctx.Writer)
+		}
+	case r.kind == FuncReqRoute && fn.Type().ConvertibleTo(reqFuncType):
+		f := fn.Convert(reqFuncType).Interface().(func(*http.Request))
+		target = func(ctx *Context) {
+			f(ctx.Request)
+		}
+	default:
+		// the function has return values, which are ignored
+		target = func(ctx *Context) {
+			var args []reflect.Value
+			switch r.kind {
+			case FuncRepReqRoute:
+				args = []reflect.Value{reflect.ValueOf(ctx.Writer), reflect.ValueOf(ctx.Request)}
+			case FuncRepRoute:
+				args = []reflect.Value{reflect.ValueOf(ctx.Writer)}
+			case FuncReqRoute:
+				args = []reflect.Value{reflect.ValueOf(ctx.Request)}
+			case FuncCtxRoute:
+				args = []reflect.Value{reflect.ValueOf(ctx)}
+			}
+			fn.Call(args)
+		}
+	}
+
+	r.chain = make([]Handler, len(r.handlers)+1)
+	copy(r.chain, r.handlers)
+	r.chain[len(r.handlers)] = target
+}
 
-	children nodes
+// Endpoint represents the routes registered by a single call to Register, i.e. a path
+// with one or more HTTP methods. It allows to configure them after the registration.
+type Endpoint struct {
+	router *Router
+	path   string
+	routes []*Route
+	name   string
+	tags   []string
+	meta   map[string]interface{}
+	table  *routeTable // the table which the routes were registered into
+}
 
-	path     string // the entire path, only presents in the "leaf" node
-	route    *Route
+// Path returns the registered path pattern.
+func (e *Endpoint) Path() string {
+	return e.path
 }
 
-func (n *node) equal(o *node) bool {
-	if n.kind == o.kind && n.segment == o.segment {
-		return true
+// Name names the endpoint, so that its URL can be built by Router.URL.
+// It panics if the name is already used by another endpoint.
+// The name must be set before the route is served, see Router.Update.
+func (e *Endpoint) Name(name string) *Endpoint {
+	check(len(name) > 0, "Route name can not be empty")
+	e.checkEditable()
+	names := e.router.editable().names
+	if other, ok := names[name]; ok && other != e {
+		panic("Route name \"" + name + "\" is already used by " + other.path)
 	}
-	return false
+	if len(e.name) > 0 && names[e.name] == e {
+		delete(names, e.name)
+	}
+	e.name = name
+	names[name] = e
+	return e
 }
 
-type nodes []*node
-
-func (e nodes) Len() int {
-	return len(e)
+// Tag adds tags to the endpoint, e.g. "admin" or "public", which the middlewares
+// can read with Context.HasTag, e.g. to label metrics. The tags must be set
+// before the route is served, see Router.Update.
+func (e *Endpoint) Tag(tags ...string) *Endpoint {
+	e.checkEditable()
+	for _, tag := range tags {
+		check(len(tag) > 0, "Route tag can not be empty")
+		if !e.hasTag(tag) {
+			e.tags = append(e.tags, tag)
+		}
+	}
+	return e
 }
 
-func (e nodes) Swap(i, j int) {
-	e[i], e[j] = e[j], e[i]
+// checkEditable panics if Router.Update is changing the routes while the endpoint is served,
+// since the requests being served read it: it can only be configured in the Update which registers it.
+func (e *Endpoint) checkEditable() {
+	check(e.table == e.router.editable(), "Route "+e.path+" is being served and can not be changed")
 }
 
-// Static route is first.
-// If two static routes, content longer is first.
-// Otherwise, first added, first matched.
-func (e nodes) Less(i, j int) bool {
-	if e[i].kind == staticNode {
-		if e[j].kind == staticNode {
-			return len(e[i].segment) > len(e[j].segment)
+func (e *Endpoint) hasTag(tag string) bool {
+	for _, t := range e.tags {
+		if t == tag {
+			return true
 		}
-		return true
 	}
-	if e[j].kind == staticNode {
-		return false
+	return false
+}
+
+// Meta sets the value of the metadata key of the endpoint, e.g. the permissions required
+// to call it or its rate-limit class, which the middlewares can read with Context.RouteMeta.
+// The metadata must be set before the route is served, see Router.Update.
+func (e *Endpoint) Meta(key string, value interface{}) *Endpoint {
+	check(len(key) > 0, "Route metadata key can not be empty")
+	e.checkEditable()
+	if e.meta == nil {
+		e.meta = make(map[string]interface{})
+	}
+	e.meta[key] = value
+	return e
+}
+
+// Default sets the value of the param key when it is missing from the matched path,
+// e.g. when the optional param of "/posts(/:page)?" is omitted.
+// The defaults must be set before the route is served, see Router.Update.
+func (e *Endpoint) Default(key, value string) *Endpoint {
+	check(len(key) > 0, "Param key can not be empty")
+	e.checkEditable()
+	for _, route := range e.routes {
+		route.defaults.Set(key, value)
 	}
-	return i < j
+	return e
 }
 
 type Router struct {
 	RoutesGroup
 
-	trees map[string]*node
-
 	AllowCustomMethod bool
 	RemoveTrailingSlash bool
+	ImplicitHead bool // answer HEAD requests with the GET route if there is no HEAD route
+	ConflictMode ConflictMode // how the conflicts between routes are handled, see Router.Conflicts
+
+	table    atomic.Value // the *routeTable being served, see Router.Update
+	updating *routeTable  // the copy of the table modified by Router.Update
+	updateMu sync.Mutex
+
+	host       string    // the host pattern of a router bound to a host
+	hostLabels []*node   // the parsed labels of the host pattern
+	parent     *Router   // the router which a router bound to a host belongs to
 }
 
-func NewRouter() *Router {
-	trees := make(map[string]*node)
-	for _, m := range Methods {
-		trees[m] = &node{}
+// settings returns the router whose options apply to r, i.e. its parent if r is bound to a host.
+func (r *Router) settings() *Router {
+	if r.parent != nil {
+		return r.parent
 	}
+	return r
+}
 
+func NewRouter() *Router {
 	r := &Router{
         RoutesGroup: RoutesGroup{
 			BasePath: "/",
 		},
-		trees: trees,
 		AllowCustomMethod: true,
 		RemoveTrailingSlash: true,
+		ImplicitHead: true,
 	}
 
 	r.RoutesGroup.router = r
+	r.table.Store(newRouteTable(r))
 
 	return r
 }
@@ -197,18 +318,50 @@ func parsePath(path string) []*node {
 			} else {
 				for i = i + 1; i < length && isAlphaNum(path[i]); i++ {
 				}
+				if i < length && path[i] == '<' {
+					for ; i < length && path[i] != '>'; i++ {
+					}
+					if i == length {
+						panic("Route path lack of '>'")
+					}
+					i++
+				}
+			}
+
+			segment := path[start : i-len(re)]
+			var constraint string
+			if idx := strings.IndexByte(segment, '<'); idx > -1 {
+				if segment[len(segment)-1] != '>' {
+					panic("Route path lack of '>'")
+				}
+				constraint = segment[idx+1 : len(segment)-1]
+				segment = segment[:idx]
 			}
 
-			if len(re) > 0 {
+			if len(constraint) > 0 {
+				validate, ok := constraints[constraint]
+				if !ok {
+					panic("Unknown route param constraint <" + constraint + ">: " + path)
+				}
+				if len(re) > 0 {
+					panic("Route param can not have both a constraint and a regexp: " + path)
+				}
+				nodes = append(nodes, &node{
+					kind:       regexNode,
+					segment:    segment,
+					constraint: constraint,
+					validate:   validate,
+				})
+			} else if len(re) > 0 {
 				nodes = append(nodes, &node{
 					kind:    regexNode,
-					segment: path[start : i-len(re)],
-					regexp:  regexp.MustCompile("(" + re + ")"),
+					segment: segment,
+					regexp:  regexp.MustCompile("^(" + re + ")$"),
 				})
 			} else {
 				nodes = append(nodes, &node{
 					kind:    namedNode,
-					segment: path[start:i],
+					segment: segment,
 				})
 			}
 
@@ -263,6 +416,79 @@ func parsePath(path string) []*node {
 	return nodes
 }
 
+// expandPath expands the optional parts of a route pattern into all the patterns
+// it stands for, the longest first. An optional part is either a group followed by '?',
+// e.g. "/posts(/:page)?", or a param followed by '?', e.g. "/files/:name.:ext?" or
+// "/posts/(:page[0-9]+)?", which is optional together with its leading '/' or '.'.
+func expandPath(path string) []string {
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '(':
+			j, depth := i, 0
+			for ; j < len(path); j++ {
+				if path[j] == '(' {
+					depth++
+				} else if path[j] == ')' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+			if j >= len(path)-1 || path[j+1] != '?' {
+				i = j
+				continue
+			}
+			with, start := path[:i]+path[i+1:j]+path[j+2:], i
+			if path[i+1] == ':' || path[i+1] == '*' {
+				// a bracketed param, e.g. "(:id[0-9]+)?", optional together with its leading '/' or '.'
+				with = path[:j+1] + path[j+2:]
+				if i > 0 && (path[i-1] == '/' || path[i-1] == '.') {
+					start = i - 1
+				}
+			}
+			return expandOptional(with, path[:start]+path[j+2:])
+		case ':':
+			j := i + 1
+			for ; j < len(path) && isAlphaNum(path[j]); j++ {
+			}
+			if j < len(path) && path[j] == '<' {
+				for ; j < len(path) && path[j] != '>'; j++ {
+				}
+				if j < len(path) {
+					j++
+				}
+			}
+			if j == len(path) || path[j] != '?' {
+				i = j - 1
+				continue
+			}
+			start := i
+			if i > 0 && (path[i-1] == '/' || path[i-1] == '.') {
+				start = i - 1
+			}
+			return expandOptional(path[:j]+path[j+1:], path[:start]+path[j+1:])
+		}
+	}
+	return []string{path}
+}
+
+func expandOptional(with, without string) []string {
+	if len(without) == 0 {
+		without = "/"
+	}
+	paths := expandPath(with)
+	outer:
+	for _, p := range expandPath(without) {
+		for _, q := range paths {
+			if p == q {
+				continue outer
+			}
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
 // validate parsed nodes, any non-static route should have static route successor
 func validateNodes(nodes []*node) bool {
 	if len(nodes) == 0 {
@@ -279,211 +505,160 @@ func validateNodes(nodes []*node) bool {
 }
 
 func (r *Router) addRoute(method, path string, route *Route) {
-	segments := parsePath(path)
-	num := len(segments)
-
-	leafNode := segments[num-1]
-	leafNode.route = route
-	leafNode.path = path
+	if paths := expandPath(path); len(paths) > 1 {
+		for _, p := range paths {
+			r.addRoute(method, p, route)
+		}
+		return
+	}
 
+	segments := parsePath(path)
 	if !validateNodes(segments) {
 		panic("Any non-static route should have static route successor: " + path)
 	}
 
-	p, ok := r.trees[method]
+	trees := r.editable().trees[r]
+	p, ok := trees[method]
 	if !ok {
-		if !r.AllowCustomMethod {
+		if !r.settings().AllowCustomMethod {
 			panic("Not allow custom method: " + method)
 		}
 		p = &node{}
-		r.trees[method] = p
+		trees[method] = p
 	}
 
-	i := 0
+	r.checkConflicts(method, path, segments, route)
 
-	outer:
-	for ; i < num; i++ {
-		for _, node := range p.children {
-			if node.equal(segments[i]) {
-				if i == num-1 {
-					// override leaf node
-					node.route = route
-					node.path = path
-				}
-				p = node
-				continue outer
-			}
-		}
-
-		p.children = append(p.children, segments[i])
-		sort.Sort(p.children) // sort by priority
-		p = segments[i]
-	}
-}
-
-func printNodes(i int, nodes []*node) {
-	for _, n := range nodes {
-		for j := 0; j < i; j++ {
-			fmt.Print("  ")
-		}
-		if i > 1 {
-			fmt.Print("┗", "  ")
-		}
-
-		fmt.Print(n.segment)
-		if len(n.path) != 0 {
-			fmt.Printf("  path[ %s ]", n.path)
-		}
-		if n.route != nil {
-			fmt.Printf("  func[ %p ]", n.route.method.Interface())
-		}
-		fmt.Println()
-		printNodes(i+1, n.children)
-	}
+	// a leaf registered twice is overridden
+	leaf := p.insert(segments)
+	leaf.route = route
+	leaf.path = path
 }
 
 func (r *Router) PrintTrees() {
-	for method, n := range r.trees {
-		if len(n.children) > 0 {
+	for method, n := range r.trees() {
+		if !n.empty() {
 			fmt.Println(method)
-			printNodes(1, n.children)
+			printNodes(1, n.children())
 			fmt.Println()
 		}
 	}
 }
 
-func (r *Router) matchNode(n *node, path string, params Params) (*node, Params, bool) {
-	tsr := false
-	if n.kind == staticNode {
-		if strings.HasPrefix(path, n.segment) {
-			if len(path) == len(n.segment) {
-				return n, params, false
-			}
+func (r *Router) Match(method, path string) (*Route, Params, bool) {
+	return r.match(method, path, make(Params, 0, strings.Count(path, "/")))
+}
 
-			for _, c := range n.children {
-				newN, newParams, newTsr := r.matchNode(c, path[len(n.segment):], params)
-				if newN != nil {
-					return newN, newParams, false
-				}
-				tsr = newTsr
-			}
+// match is like Match, but it appends the params of the route to params,
+// so that the buffer of the params of a Context is reused.
+func (r *Router) match(method, path string, params Params) (*Route, Params, bool) {
+	cn, ok := r.trees()[method]
+	if !ok {
+		return nil, nil, false
+	}
 
-			if path == n.segment + "/" {
-				tsr = true
-			}
-		}
-	} else if n.kind == anyNode {
-		for _, c := range n.children {
-			idx := strings.LastIndex(path, c.segment)
-			if idx > -1 {
-				params = append(params, Param{n.segment, path[:idx]})
-				return r.matchNode(c, path[idx:], params)
-			}
-		}
+	if leaf, ps := cn.lookup(path, params, nil); leaf != nil {
+		return leaf.route, leaf.route.withDefaults(ps), false
+	}
 
-		return n, append(params, Param{n.segment, path}), false
-	} else if n.kind == namedNode {
-		for _, c := range n.children {
-			idx := strings.Index(path, c.segment)
-			if idx > -1 {
-				params = append(params, Param{n.segment, path[:idx]})
-				return r.matchNode(c, path[idx:], params)
-			}
-		}
+	// whether the path with or without a trailing slash matches a route
+	var tsr bool
+	if len(path) > 1 && path[len(path)-1] == '/' {
+		leaf, _ := cn.lookup(path[:len(path)-1], params, nil)
+		tsr = leaf != nil
+	} else if len(path) > 0 {
+		leaf, _ := cn.lookup(path+"/", params, nil)
+		tsr = leaf != nil
+	}
+	return nil, nil, tsr
+}
 
-		idx := strings.IndexByte(path, '/')
-		if idx == -1 {
-			params = append(params, Param{n.segment, path})
-			return n, params, false
-		}
+// fixPath returns path in the casing of the route of method which matches it, ignoring
+// the case of the static parts of the patterns if fold is true, and whether there is such a route.
+func (r *Router) fixPath(method, path string, fold bool) (string, bool) {
+	cn, ok := r.trees()[method]
+	if !ok {
+		return path, false
+	}
+	if !fold {
+		leaf, _ := cn.lookup(path, nil, nil)
+		return path, leaf != nil
+	}
+	buf := []byte(path)
+	leaf, _ := cn.lookup(path, nil, buf)
+	return string(buf), leaf != nil
+}
 
-		if idx == len(path)-1 {
-			tsr = true
+// allowed returns the value of the Allow header for path, i.e. the sorted methods
+// which have a route matching path, or an empty string if there is none.
+// The path "*" matches any route. OPTIONS is included if options is true.
+func (r *Router) allowed(path string, options bool) string {
+	trees := r.trees()
+	methods := make([]string, 0, len(trees)+1)
+	for method, root := range trees {
+		if method == "OPTIONS" && options {
+			continue
 		}
-	} else if n.kind == regexNode {
-		idx := strings.IndexByte(path, '/')
-		if idx > -1 {
-			if n.regexp.MatchString(path[:idx]) {
-				for _, c := range n.children {
-					newN, newParams, newTsr := r.matchNode(c, path[idx:], params)
-					if newN != nil {
-						return newN, append(Params{Param{n.segment, path[:idx]}}, newParams...), false
-					}
-					tsr = newTsr
-				}
-
-				if idx == len(path)-1 {
-					tsr = true
-				}
-			}
-		} else {
-			for _, c := range n.children {
-				idx := strings.Index(path, c.segment)
-				if idx > -1 && n.regexp.MatchString(path[:idx]) {
-					params = append(params, Param{n.segment, path[:idx]})
-					return r.matchNode(c, path[idx:], params)
-				}
-			}
-
-			if n.regexp.MatchString(path) {
-				params = append(params, Param{n.segment, path})
-				return n, params, false
+		if path == "*" {
+			if !root.empty() {
+				methods = append(methods, method)
 			}
+		} else if route, _, _ := r.Match(method, path); route != nil {
+			methods = append(methods, method)
 		}
 	}
-
-	return nil, nil, tsr
+	if len(methods) == 0 {
+		return ""
+	}
+	if r.settings().ImplicitHead {
+		var get, head bool
+		for _, m := range methods {
+			get = get || m == "GET"
+			head = head || m == "HEAD"
+		}
+		if get && !head {
+			methods = append(methods, "HEAD")
+		}
+	}
+	if options {
+		methods = append(methods, "OPTIONS")
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
 }
 
-func (r *Router) Match(method, path string) (*Route, Params, bool) {
-	cn, ok := r.trees[method]
-	if !ok {
-		return nil, nil, false
+// funcKind returns the RouteKind of a function type, and its typedHandler if it is a typed one.
+// It returns false if the function type can not handle requests.
+func funcKind(t reflect.Type) (RouteKind, *typedHandler, bool) {
+	if typed := newTypedHandler(t); typed != nil {
+		return FuncTypedRoute, typed, true
 	}
 
-	params := make(Params, 0, strings.Count(path, "/"))
-	tsr := false
-
-	for _, n := range cn.children {
-		newN, newParams, newTsr := r.matchNode(n, path, params)
-		if newN != nil {
-			return newN.route, newParams, tsr
-		}
-		if newTsr {
-			tsr = true
+	if t.NumIn() == 0 {
+		return FuncRoute, nil, true
+	} else if t.NumIn() == 1 {
+		if t.In(0) == contextType {
+			return FuncCtxRoute, nil, true
+		} else if t.In(0) == reflect.TypeOf(&http.Request{}) {
+			return FuncReqRoute, nil, true
+		} else if isResponseWriter(t.In(0)) {
+			return FuncRepRoute, nil, true
 		}
+	} else if t.NumIn() == 2 && isResponseWriter(t.In(0)) && t.In(1) == reflect.TypeOf(&http.Request{}) {
+		return FuncRepReqRoute, nil, true
 	}
+	return 0, nil, false
+}
 
-	return nil, nil, tsr
+func isResponseWriter(t reflect.Type) bool {
+	return t.Kind() == reflect.Interface && t.Name() == "ResponseWriter" && t.PkgPath() == "net/http"
 }
 
-func (r *Router) addFunc(methods []string, path string, function interface{}, handlers []Handler) {
+func (r *Router) addFunc(methods []string, path string, function interface{}, handlers []Handler) *Route {
 	v := reflect.ValueOf(function)
-    t := v.Type()
-
-	var kind RouteKind
 
-	if t.NumIn() == 0 {
-		kind = FuncRoute
-	} else if t.NumIn() == 1 {
-		if t.In(0) == reflect.TypeOf(&Context{}) {
-			kind = FuncCtxRoute
-		} else if t.In(0) == reflect.TypeOf(&http.Request{}) {
-			kind = FuncReqRoute
-		} else if t.In(0).Kind() == reflect.Interface &&
-			t.In(0).Name() == "ResponseWriter" &&
-			t.In(0).PkgPath() == "net/http" {
-			kind = FuncRepRoute
-		} else {
-			panic(fmt.Sprintln("Invalid function type", methods, path, function))
-		}
-	} else if t.NumIn() == 2 &&
-		t.In(0).Kind() == reflect.Interface &&
-		t.In(0).Name() == "ResponseWriter" &&
-		t.In(0).PkgPath() == "net/http" &&
-		t.In(1) == reflect.TypeOf(&http.Request{}) {
-		kind = FuncRepReqRoute
-	} else {
+	kind, typed, ok := funcKind(v.Type())
+	if !ok {
 		panic(fmt.Sprintln("Invalid function type", methods, path, function))
 	}
 
@@ -491,16 +666,24 @@ func (r *Router) addFunc(methods []string, path string, function interface{}, ha
 		kind: kind,
 		method: v,
 		handlers: handlers,
+		path: path,
+		handlerName: nameOfFunction(function),
+		typed: typed,
 	}
+	route.build()
 	for _, m := range methods {
 		r.addRoute(m, path, route)
+		debugPrintRoute(m, path, route.handlerName, handlers)
 	}
+	return route
 }
 
-func (r *Router) addStruct(methods map[string]string, path string, structPtr interface{}, handlers []Handler) {
+func (r *Router) addStruct(methods map[string]string, path string, structPtr interface{}, handlers []Handler) []*Route {
 	v := reflect.ValueOf(structPtr)
 	t := v.Type()
 
+	var routes []*Route
+
 	for verb, name := range methods {
 		method, ok := t.MethodByName(name)
 		if !ok {
@@ -511,80 +694,162 @@ func (r *Router) addStruct(methods map[string]string, path string, structPtr int
 			continue
 		}
 
-		var kind RouteKind
-
-		mt := method.Type
-		if mt.NumIn() == 1 {
-			kind = FuncRoute
-		} else if mt.NumIn() == 2 {
-			if mt.In(1) == reflect.TypeOf(&Context{}) {
-				kind = FuncCtxRoute
-			} else if t.In(1) == reflect.TypeOf(&http.Request{}) {
-				kind = FuncReqRoute
-			} else if t.In(1).Kind() == reflect.Interface &&
-				t.In(1).Name() == "ResponseWriter" &&
-				t.In(1).PkgPath() == "net/http" {
-				kind = FuncRepRoute
-			} else {
-				panic(fmt.Sprintln("Invalid function type", methods, path, mt))
-			}
-		} else if t.NumIn() == 3 &&
-			t.In(1).Kind() == reflect.Interface &&
-			t.In(1).Name() == "ResponseWriter" &&
-			t.In(1).PkgPath() == "net/http" &&
-			t.In(2) == reflect.TypeOf(&http.Request{}) {
-			kind = FuncRepReqRoute
-		} else {
-			panic(fmt.Sprintln("Invalid function type", methods, path, mt))
+		// the method value, bound to the struct pointer
+		fn := v.Method(method.Index)
+		if h := controllerHandler(structPtr, method.Name); h != nil {
+			fn = reflect.ValueOf(h)
 		}
-
-		f := func(in []reflect.Value) []reflect.Value {
-			in = append([]reflect.Value{v}, in...)
-			return method.Func.Call(in)
+		kind, typed, ok := funcKind(fn.Type())
+		if !ok {
+			panic(fmt.Sprintln("Invalid function type", methods, path, method.Type))
 		}
-		r.addRoute(verb, path, &Route{
+
+		route := &Route{
 			kind: kind,
-			method: reflect.ValueOf(f),
+			method: fn,
 			handlers: handlers,
-		})
+			path: path,
+			handlerName: nameOfFunction(method.Func.Interface()),
+			controller: true,
+			typed: typed,
+		}
+		route.build()
+		r.addRoute(verb, path, route)
+		routes = append(routes, route)
+		debugPrintRoute(verb, path, route.handlerName, handlers)
+	}
+	return routes
+}
+
+// controllerHandler returns the method of a controller with one of the names which Register
+// and Resource look up, e.g. Get or Show, as a Handler if it is a func(*Context), or else nil.
+// Unlike a method value of reflect, it is called without reflection, and so without allocating.
+func controllerHandler(controller interface{}, name string) Handler {
+	switch name {
+	case "Get":
+		if c, ok := controller.(interface{ Get(*Context) }); ok {
+			return c.Get
+		}
+	case "Post":
+		if c, ok := controller.(interface{ Post(*Context) }); ok {
+			return c.Post
+		}
+	case "Head":
+		if c, ok := controller.(interface{ Head(*Context) }); ok {
+			return c.Head
+		}
+	case "Delete":
+		if c, ok := controller.(interface{ Delete(*Context) }); ok {
+			return c.Delete
+		}
+	case "Put":
+		if c, ok := controller.(interface{ Put(*Context) }); ok {
+			return c.Put
+		}
+	case "Options":
+		if c, ok := controller.(interface{ Options(*Context) }); ok {
+			return c.Options
+		}
+	case "Trace":
+		if c, ok := controller.(interface{ Trace(*Context) }); ok {
+			return c.Trace
+		}
+	case "Patch":
+		if c, ok := controller.(interface{ Patch(*Context) }); ok {
+			return c.Patch
+		}
+	case "Any":
+		if c, ok := controller.(interface{ Any(*Context) }); ok {
+			return c.Any
+		}
+	case "Index":
+		if c, ok := controller.(interface{ Index(*Context) }); ok {
+			return c.Index
+		}
+	case "New":
+		if c, ok := controller.(interface{ New(*Context) }); ok {
+			return c.New
+		}
+	case "Create":
+		if c, ok := controller.(interface{ Create(*Context) }); ok {
+			return c.Create
+		}
+	case "Show":
+		if c, ok := controller.(interface{ Show(*Context) }); ok {
+			return c.Show
+		}
+	case "Edit":
+		if c, ok := controller.(interface{ Edit(*Context) }); ok {
+			return c.Edit
+		}
+	case "Update":
+		if c, ok := controller.(interface{ Update(*Context) }); ok {
+			return c.Update
+		}
+	case "Destroy":
+		if c, ok := controller.(interface{ Destroy(*Context) }); ok {
+			return c.Destroy
+		}
 	}
+	return nil
 }
 
-func (r *Router) Register(methods interface{}, path string, target interface{}, handlers ...Handler) {
+// Register registers the target for the HTTP methods and the path, with the handlers as middlewares.
+// The methods may be a string or a []string. The target may be a function, or a pointer to a struct
+// whose methods named after the HTTP methods, e.g. Get, or else Any, handle the requests.
+// A function may also take a pointer to a request struct, which is bound and validated,
+// and return a result which is rendered, with an error, e.g. func(*Context, *Req) (*Resp, error).
+// The returned Endpoint allows to configure the registered routes further, e.g. to name them.
+func (r *Router) Register(methods interface{}, path string, target interface{}, handlers ...Handler) *Endpoint {
 	check(path[0] == '/', "Path must begin with '/'")
 
 	if len(path) > 1 && path[len(path)-1] == '/' {
-		if r.RemoveTrailingSlash {
+		if r.settings().RemoveTrailingSlash {
 			path = strings.TrimRight(path, "/")
 		} else {
 			panic("Path should not have trailing slash")
 		}
 	}
 
-	var ms []string
-	switch methods.(type) {
-	case string:
-		ms = []string{methods.(string)}
-	case []string:
-		ms = methods.([]string)
-	default:
-		panic("Invalid HTTP methods")
-	}
-	for _, m := range ms {
-		check(len(m) > 0, "HTTP method can not be empty")
-	}
+	ms := parseMethods(methods)
 
 	v := reflect.ValueOf(target)
 
+	endpoint := &Endpoint{
+		router: r,
+		path: path,
+		table: r.editable(),
+	}
 	if v.Kind() == reflect.Func {
-		r.addFunc(ms, path, target, handlers)
+		endpoint.routes = []*Route{r.addFunc(ms, path, target, handlers)}
 	} else if v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct {
 		var mm = make(map[string]string)
 		for _, m := range ms {
 			mm[m] = strings.Title(strings.ToLower(m))
 		}
-		r.addStruct(mm, path, target, handlers)
+		endpoint.routes = r.addStruct(mm, path, target, handlers)
 	} else {
 		panic("Invalid route handler")
 	}
+	for _, route := range endpoint.routes {
+		route.endpoint = endpoint
+	}
+	return endpoint
+}
+
+// parseMethods returns the HTTP methods given as a string or a []string.
+func parseMethods(methods interface{}) []string {
+	var ms []string
+	switch methods.(type) {
+	case string:
+		ms = []string{methods.(string)}
+	case []string:
+		ms = methods.([]string)
+	default:
+		panic("Invalid HTTP methods")
+	}
+	for _, m := range ms {
+		check(len(m) > 0, "HTTP method can not be empty")
+	}
+	return ms
 }
@@ -1,9 +1,12 @@
 package mel
 
 import (
-	"testing"
-	"regexp"
 	"log"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
 )
 
 var (
@@ -189,6 +192,39 @@ var (
 		"/web/content/(:id3)-(:unique3)/(:filename)": {
 			{"/web/content/36-0420888/website.assets_frontend.0.css", true, Params{{":id3", "36"}, {":unique3", "0420888"}, {":filename", "website.assets_frontend.0.css"}}},
 		},
+		"/users/:id<int>": {
+			{"/users/42", true, Params{{":id", "42"}}},
+			{"/users/-1", true, Params{{":id", "-1"}}},
+			{"/users/42a", false, Params{}},
+			{"/users/", false, Params{}},
+		},
+		"/users/:id<int>/edit": {
+			{"/users/42/edit", true, Params{{":id", "42"}}},
+			{"/users/jack/edit", false, Params{}},
+		},
+		"/tags/(:slug<alpha>).html": {
+			{"/tags/go.html", true, Params{{":slug", "go"}}},
+			{"/tags/go1.html", false, Params{}},
+		},
+		"/posts(/:page)?": {
+			{"/posts", true, Params{}},
+			{"/posts/2", true, Params{{":page", "2"}}},
+			{"/posts/2/3", false, Params{}},
+		},
+		"/files/:name.:ext?": {
+			{"/files/main.go", true, Params{{":name", "main"}, {":ext", "go"}}},
+			{"/files/LICENSE", true, Params{{":name", "LICENSE"}}},
+		},
+		"/archive(/(:year[0-9]+)(/:month<int>)?)?": {
+			{"/archive", true, Params{}},
+			{"/archive/2017", true, Params{{":year", "2017"}}},
+			{"/archive/2017/05", true, Params{{":year", "2017"}, {":month", "05"}}},
+		},
+		"/orders/:uid<uuid>": {
+			{"/orders/123e4567-e89b-12d3-a456-426655440000", true, Params{{":uid", "123e4567-e89b-12d3-a456-426655440000"}}},
+			{"/orders/123e4567-e89b-12d3-a456-42665544000g", false, Params{}},
+			{"/orders/42", false, Params{}},
+		},
 	}
 )
 
@@ -281,8 +317,9 @@ var (
 			[]string{"/*name", "/:name"},
 			[]MatchResult{
 				{"/", false, Params{}},
-				{"/s", true, Params{{"*name", "s"}}},
-				{"/123", true, Params{{"*name", "123"}}},
+				{"/s", true, Params{{":name", "s"}}},
+				{"/123", true, Params{{":name", "123"}}},
+				{"/123/1", true, Params{{"*name", "123/1"}}},
 			},
 		},
 
@@ -312,8 +349,9 @@ var (
 			[]MatchResult{
 				{"/", false, Params{}},
 				{"/admin/ui", true, Params{}},
-				{"/s", true, Params{{"*name", "s"}}},
-				{"/123", true, Params{{"*name", "123"}}},
+				{"/s", true, Params{{":name", "s"}}},
+				{"/123", true, Params{{":name", "123"}}},
+				{"/admin/ui/1", true, Params{{"*name", "admin/ui/1"}}},
 			},
 		},
 
@@ -327,6 +365,23 @@ var (
 			},
 		},
 
+		{
+			[]string{"/users/:id<int>", "/users/:name"},
+			[]MatchResult{
+				{"/users/42", true, Params{{":id", "42"}}},
+				{"/users/jack", true, Params{{":name", "jack"}}},
+			},
+		},
+
+		{
+			[]string{"/users/:id<int>/edit", "/users/:name<alpha>/edit"},
+			[]MatchResult{
+				{"/users/42/edit", true, Params{{":id", "42"}}},
+				{"/users/jack/edit", true, Params{{":name", "jack"}}},
+				{"/users/jack42/edit", false, Params{}},
+			},
+		},
+
 		{
 			[]string{"/admin/ui", "/:name1/:name2"},
 			[]MatchResult{
@@ -391,3 +446,146 @@ func TestRoutingMultiple(t *testing.T) {
 	}
 }
 
+
+func TestRouteConstraints(t *testing.T) {
+	RegisterConstraint("even", func(value string) bool {
+		n, err := strconv.Atoi(value)
+		return err == nil && n%2 == 0
+	})
+	defer delete(constraints, "even")
+
+	router := NewRouter()
+	router.Register("GET", "/numbers/:n<even>", func() {})
+	route, params, _ := router.Match("GET", "/numbers/42")
+	if route == nil || params.Get("n") != "42" {
+		t.Fatal("/numbers/42 should match", params)
+	}
+	if route, _, _ := router.Match("GET", "/numbers/41"); route != nil {
+		t.Fatal("/numbers/41 should not match")
+	}
+
+	for _, path := range []string{"/users/:id<unknown>", "/users/:id<int", "/users/(:id<int>[0-9]+)"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal(path, "should panic")
+				}
+			}()
+			router.Register("GET", path, func() {})
+		}()
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	expands := map[string][]string{
+		"/posts":                   {"/posts"},
+		"/(:id[0-9]+)":             {"/(:id[0-9]+)"},
+		"/posts(/:page)?":          {"/posts/:page", "/posts"},
+		"/posts/(:page[0-9]+)?":    {"/posts/(:page[0-9]+)", "/posts"},
+		"/files/:name.:ext?":       {"/files/:name.:ext", "/files/:name"},
+		"/users/:id<int>?":         {"/users/:id<int>", "/users"},
+		"(/:lang)?":                {"/:lang", "/"},
+		"/a(/:b(/:c)?)?":           {"/a/:b/:c", "/a/:b", "/a"},
+		"/:name?/edit(/:section)?": {"/:name/edit/:section", "/:name/edit", "/edit/:section", "/edit"},
+	}
+	for path, expect := range expands {
+		result := expandPath(path)
+		if len(result) != len(expect) {
+			t.Fatalf("%v 's result %v is not equal %v", path, result, expect)
+		}
+		for i := range expect {
+			if result[i] != expect[i] {
+				t.Fatalf("%v 's result %v is not equal %v", path, result, expect)
+			}
+		}
+	}
+}
+
+// discardWriter is a http.ResponseWriter which allocates nothing.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+func newDispatchRouter() *Mel {
+	router := New()
+	router.Use(func(c *Context) { c.Next() })
+	router.Get("/func", func() {})
+	router.Get("/ctx", func(c *Context) {})
+	router.Get("/ctx/:id", func(c *Context) {})
+	router.Get("/http", func(w http.ResponseWriter, req *http.Request) {})
+	router.Register("GET", "/struct", &routesController{})
+	router.Resource("/posts", &postsController{})
+	return router
+}
+
+func TestDispatchAllocs(t *testing.T) {
+	router := newDispatchRouter()
+	w := &discardWriter{header: http.Header{}}
+	for _, r := range [][2]string{
+		{"GET", "/func"}, {"GET", "/ctx"}, {"GET", "/ctx/42"}, {"GET", "/http"}, {"GET", "/struct"}, {"DELETE", "/posts/42"},
+	} {
+		req, _ := http.NewRequest(r[0], r[1], nil)
+		allocs := testing.AllocsPerRun(100, func() {
+			router.ServeHTTP(w, req)
+		})
+		if allocs != 0 {
+			t.Fatal(r[0], r[1], "allocates", allocs, "times per request")
+		}
+	}
+}
+
+func benchmarkDispatch(b *testing.B, path string) {
+	router := newDispatchRouter()
+	w := &discardWriter{header: http.Header{}}
+	req, _ := http.NewRequest("GET", path, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkDispatchFunc(b *testing.B)   { benchmarkDispatch(b, "/func") }
+func BenchmarkDispatchCtx(b *testing.B)    { benchmarkDispatch(b, "/ctx") }
+func BenchmarkDispatchParam(b *testing.B)  { benchmarkDispatch(b, "/ctx/42") }
+func BenchmarkDispatchHTTP(b *testing.B)   { benchmarkDispatch(b, "/http") }
+func BenchmarkDispatchStruct(b *testing.B) { benchmarkDispatch(b, "/struct") }
+
+// BenchmarkExecuteReflect measures the previous implementation of Route.execute,
+// which built the chain and called the target through reflection on every request.
+func BenchmarkExecuteReflect(b *testing.B) {
+	handlers := []Handler{func(c *Context) { c.Next() }}
+	method := reflect.ValueOf(func(c *Context) {})
+	c := newContext()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		target := func(ctx *Context) {
+			method.Call([]reflect.Value{reflect.ValueOf(ctx)})
+		}
+		c.handlers = append(handlers, target)
+		c.index = preStartIndex
+		c.Next()
+	}
+}
+
+func BenchmarkExecute(b *testing.B) {
+	route := &Route{
+		kind:     FuncCtxRoute,
+		method:   reflect.ValueOf(func(c *Context) {}),
+		handlers: []Handler{func(c *Context) { c.Next() }},
+	}
+	route.build()
+	c := newContext()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		route.execute(c)
+		c.index = preStartIndex
+		c.Next()
+	}
+}
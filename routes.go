@@ -0,0 +1,91 @@
+package mel
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method      string                 `json:"method"`
+	Host        string                 `json:"host,omitempty"` // the host pattern, see RoutesGroup.Host
+	Path        string                 `json:"path"`
+	Name        string                 `json:"name,omitempty"`
+	Handler     string                 `json:"handler"`
+	Middlewares int                    `json:"middlewares"`
+	Kind        string                 `json:"kind"` // "func" or "struct"
+	Tags        []string               `json:"tags,omitempty"`
+	Meta        map[string]interface{} `json:"meta,omitempty"`
+}
+
+// RoutesInfo is a list of routes, as returned by Router.Routes.
+type RoutesInfo []RouteInfo
+
+const (
+	funcRouteKind   = "func"
+	structRouteKind = "struct"
+)
+
+// Routes returns all the registered routes, sorted by host, by path and then by method,
+// so that the route tables of two versions of an app can be compared.
+func (r *Router) Routes() RoutesInfo {
+	var routes RoutesInfo
+	table := r.served()
+	for _, router := range append([]*Router{r.settings()}, table.hosts...) {
+		for method, root := range table.trees[router] {
+			routes = collectRoutes(routes, router.host, method, root)
+		}
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Host != routes[j].Host {
+			return routes[i].Host < routes[j].Host
+		}
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+func collectRoutes(routes RoutesInfo, host, method string, n *node) RoutesInfo {
+	if n.route != nil {
+		info := RouteInfo{
+			Method:      method,
+			Host:        host,
+			Path:        n.path,
+			Handler:     n.route.handlerName,
+			Middlewares: len(n.route.handlers),
+			Kind:        funcRouteKind,
+		}
+		if n.route.controller {
+			info.Kind = structRouteKind
+		}
+		if n.route.endpoint != nil {
+			info.Name = n.route.endpoint.name
+			info.Tags = n.route.endpoint.tags
+			info.Meta = n.route.endpoint.meta
+		}
+		routes = append(routes, info)
+	}
+	for _, c := range n.children() {
+		routes = collectRoutes(routes, host, method, c)
+	}
+	return routes
+}
+
+// WriteJSON writes the routes to w as an indented JSON array, one route per object.
+func (routes RoutesInfo) WriteJSON(w io.Writer) error {
+	if routes == nil {
+		routes = RoutesInfo{}
+	}
+	data, err := json.MarshalIndent(routes, "", "    ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = w.Write(data)
+	return err
+}
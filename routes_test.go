@@ -0,0 +1,75 @@
+package mel
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type routesController struct{}
+
+func (*routesController) Get(c *Context)  {}
+func (*routesController) Post(c *Context) {}
+
+func routesHandler(c *Context) {}
+
+func TestRoutes(t *testing.T) {
+	router := New()
+	router.Use(func(c *Context) {})
+	router.Get("/users/:id", routesHandler).Name("user")
+	router.Group("/admin", func(c *Context) {}).Post("/users", routesHandler)
+	router.Register([]string{"GET", "POST", "PUT"}, "/posts", &routesController{})
+
+	routes := router.Routes()
+	assert.Equal(t, RoutesInfo{
+		{Method: "POST", Path: "/admin/users", Handler: "github.com/ridewindx/mel.routesHandler", Middlewares: 2, Kind: "func"},
+		{Method: "GET", Path: "/posts", Handler: "github.com/ridewindx/mel.(*routesController).Get", Middlewares: 0, Kind: "struct"},
+		{Method: "POST", Path: "/posts", Handler: "github.com/ridewindx/mel.(*routesController).Post", Middlewares: 0, Kind: "struct"},
+		{Method: "GET", Path: "/users/:id", Name: "user", Handler: "github.com/ridewindx/mel.routesHandler", Middlewares: 1, Kind: "func"},
+	}, routes)
+}
+
+func TestRoutesWriteJSON(t *testing.T) {
+	router := New()
+	router.Get("/users/:id", routesHandler).Name("user")
+
+	var buf bytes.Buffer
+	assert.NoError(t, router.Routes().WriteJSON(&buf))
+	assert.Equal(t, `[
+    {
+        "method": "GET",
+        "path": "/users/:id",
+        "name": "user",
+        "handler": "github.com/ridewindx/mel.routesHandler",
+        "middlewares": 0,
+        "kind": "func"
+    }
+]
+`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, New().Routes().WriteJSON(&buf))
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestRoutesMetadata(t *testing.T) {
+	router := New()
+	router.Get("/admin/users", routesHandler).
+		Name("users").
+		Tag("admin", "internal", "admin").
+		Meta("permissions", []string{"users:read"}).
+		Meta("ratelimit", "low")
+
+	routes := router.Routes()
+	if assert.Len(t, routes, 1) {
+		assert.Equal(t, []string{"admin", "internal"}, routes[0].Tags)
+		assert.Equal(t, map[string]interface{}{
+			"permissions": []string{"users:read"},
+			"ratelimit":   "low",
+		}, routes[0].Meta)
+	}
+
+	assert.Panics(t, func() { router.Get("/a", routesHandler).Tag("") })
+	assert.Panics(t, func() { router.Get("/b", routesHandler).Meta("", 1) })
+}
@@ -0,0 +1,124 @@
+package mel
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// shutdownPollInterval is how often Shutdown checks whether active requests have finished.
+const shutdownPollInterval = 10 * time.Millisecond
+
+// OnStart registers hooks to be called every time the app starts serving,
+// right before it accepts connections.
+// If a hook returns an error, the server is not started and the error is returned.
+func (mel *Mel) OnStart(hooks ...func() error) {
+	mel.onStart = append(mel.onStart, hooks...)
+}
+
+// OnShutdown registers hooks to be called by Shutdown,
+// after all the active requests have finished or the shutdown context is done.
+// The hooks are called once, by the first call to Shutdown.
+func (mel *Mel) OnShutdown(hooks ...func()) {
+	mel.onShutdown = append(mel.onShutdown, hooks...)
+}
+
+// Serve accepts incoming HTTP connections on the listener l and serves them with `mel`.
+// Note: this method will block the calling goroutine until Shutdown is called or an error happens.
+func (mel *Mel) Serve(l net.Listener) error {
+	srv := &http.Server{Handler: mel}
+	return mel.serve(srv, func() error {
+		return srv.Serve(l)
+	})
+}
+
+func (mel *Mel) serve(srv *http.Server, serve func() error) error {
+	mel.serversMu.Lock()
+	if mel.ShuttingDown() {
+		mel.serversMu.Unlock()
+		return http.ErrServerClosed
+	}
+	mel.servers[srv] = struct{}{}
+	mel.serversMu.Unlock()
+
+	defer func() {
+		mel.serversMu.Lock()
+		delete(mel.servers, srv)
+		mel.serversMu.Unlock()
+	}()
+
+	for _, hook := range mel.onStart {
+		if err := hook(); err != nil {
+			return err
+		}
+	}
+
+	err := serve()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// ShuttingDown returns true if Shutdown has been called.
+func (mel *Mel) ShuttingDown() bool {
+	select {
+	case <-mel.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown gracefully shuts down all the servers started by Run, RunTLS, RunUnix and Serve.
+// It stops accepting new connections, ends the streams started by Context.Stream and Context.SSE,
+// and waits for the active requests to finish. Then it calls the hooks registered by OnShutdown, the first time it is called.
+// If ctx is done before all the requests have finished, Shutdown returns the context's error.
+// Once Shutdown has been called, the app can not be started again.
+func (mel *Mel) Shutdown(ctx context.Context) error {
+	mel.serversMu.Lock()
+	mel.closeOnce.Do(func() { close(mel.closing) })
+	servers := make([]*http.Server, 0, len(mel.servers))
+	for srv := range mel.servers {
+		servers = append(servers, srv)
+	}
+	mel.serversMu.Unlock()
+
+	var err error
+	for _, srv := range servers {
+		if e := srv.Shutdown(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	// Requests may also come through servers not owned by mel, e.g. httptest.Server,
+	// or from hijacked connections, which http.Server.Shutdown does not wait for.
+	if e := mel.waitActive(ctx); e != nil && err == nil {
+		err = e
+	}
+
+	mel.hooksOnce.Do(func() {
+		for _, hook := range mel.onShutdown {
+			hook()
+		}
+	})
+
+	return err
+}
+
+func (mel *Mel) waitActive(ctx context.Context) error {
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for {
+		if atomic.LoadInt64(&mel.active) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package mel
+
+import (
+	"strings"
+)
+
+// routeTable holds the routes of a router and of the routers bound to its hosts.
+// The table being served is only modified in place before the router serves requests:
+// Router.Update modifies a copy of it, which then replaces it atomically.
+type routeTable struct {
+	trees     map[*Router]map[string]*node // the trees of the router and of its host routers, by method
+	hosts     []*Router                    // the routers bound to a host pattern, see RoutesGroup.Host
+	names     map[string]*Endpoint         // named endpoints
+	patterns  map[string][]*pattern        // the registered patterns by router, method and shape, see checkConflicts
+	conflicts []RouteConflict
+}
+
+func newRouteTable(r *Router) *routeTable {
+	return &routeTable{
+		trees:    map[*Router]map[string]*node{r: newTrees()},
+		names:    make(map[string]*Endpoint),
+		patterns: make(map[string][]*pattern),
+	}
+}
+
+func newTrees() map[string]*node {
+	trees := make(map[string]*node, len(Methods))
+	for _, m := range Methods {
+		trees[m] = &node{}
+	}
+	return trees
+}
+
+// clone returns a copy of the table, whose trees can be modified without affecting t.
+func (t *routeTable) clone() *routeTable {
+	c := &routeTable{
+		trees:     make(map[*Router]map[string]*node, len(t.trees)),
+		hosts:     append([]*Router(nil), t.hosts...),
+		names:     make(map[string]*Endpoint, len(t.names)),
+		patterns:  make(map[string][]*pattern, len(t.patterns)),
+		conflicts: append([]RouteConflict(nil), t.conflicts...),
+	}
+	for r, trees := range t.trees {
+		cloned := make(map[string]*node, len(trees))
+		for method, root := range trees {
+			cloned[method] = root.clone()
+		}
+		c.trees[r] = cloned
+	}
+	for name, e := range t.names {
+		c.names[name] = e
+	}
+	for key, patterns := range t.patterns {
+		c.patterns[key] = append([]*pattern(nil), patterns...)
+	}
+	return c
+}
+
+// served returns the table being served by the router, or by its parent if it is bound to a host.
+func (r *Router) served() *routeTable {
+	return r.settings().table.Load().(*routeTable)
+}
+
+// editable returns the table which the routes are registered into, i.e. the copy
+// being modified by Update, or else the table being served.
+func (r *Router) editable() *routeTable {
+	if root := r.settings(); root.updating != nil {
+		return root.updating
+	}
+	return r.served()
+}
+
+// trees returns the trees of the router being served, by method.
+func (r *Router) trees() map[string]*node {
+	return r.served().trees[r]
+}
+
+// Update allows to change the routes while the router serves requests, e.g. to register
+// or unregister the routes of a plugin at runtime. The routes registered and unregistered
+// by fn, through the router or any of its groups, are applied to a copy of the routes,
+// which replaces them atomically when fn returns, so that the requests being served are not affected,
+// and that the next ones see all the changes. The routes are left unchanged if fn panics.
+//
+// The updates are serialized, and fn must not call Update. The names, the defaults, the tags and the metadata
+// of the routes being served can not be changed: Endpoint.Name, Endpoint.Default, Endpoint.Tag
+// and Endpoint.Meta panic if fn calls them on the endpoint of a route registered before.
+// Outside of Update, the routes must only be changed before the router serves requests.
+func (r *Router) Update(fn func()) {
+	root := r.settings()
+	root.updateMu.Lock()
+	defer root.updateMu.Unlock()
+
+	root.updating = root.served().clone()
+	defer func() {
+		root.updating = nil
+	}()
+
+	fn()
+	root.table.Store(root.updating)
+}
+
+// Unregister removes the routes registered for the HTTP methods and the path, and reports
+// whether there were any. The methods may be a string or a []string, as for Register.
+// The name of the endpoint of the routes is released once all its routes are removed.
+// At runtime, it must be called in Router.Update.
+func (group *RoutesGroup) Unregister(methods interface{}, relativePath string) bool {
+	return group.router.unregister(parseMethods(methods), joinPaths(group.BasePath, relativePath))
+}
+
+func (r *Router) unregister(methods []string, path string) bool {
+	if len(path) > 1 && r.settings().RemoveTrailingSlash {
+		path = strings.TrimRight(path, "/")
+	}
+
+	table := r.editable()
+	var removed bool
+	for _, method := range methods {
+		root, ok := table.trees[r][method]
+		if !ok {
+			continue
+		}
+		for _, p := range expandPath(path) {
+			segments := parsePath(p)
+			route := root.remove(segments)
+			if route == nil {
+				continue
+			}
+			removed = true
+			r.forgetPattern(method, p, segments)
+			if route.endpoint != nil {
+				r.releaseName(route.endpoint, table)
+			}
+		}
+	}
+	return removed
+}
+
+// releaseName releases the name of the endpoint of an unregistered route,
+// once none of the routes of the endpoint is registered anymore.
+func (r *Router) releaseName(e *Endpoint, table *routeTable) {
+	if len(e.name) == 0 || table.names[e.name] != e {
+		return
+	}
+	for _, root := range table.trees[r] {
+		for _, p := range expandPath(e.path) {
+			if leaf := root.find(parsePath(p)); leaf != nil && leaf.route != nil && leaf.route.endpoint == e {
+				return
+			}
+		}
+	}
+	delete(table.names, e.name)
+}
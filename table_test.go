@@ -0,0 +1,184 @@
+package mel
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnregister(t *testing.T) {
+	router := New()
+	router.Get("/users/:id", func(c *Context) { c.Text(200, "user") }).Name("user")
+	router.Register([]string{"GET", "POST"}, "/posts(/:page)?", func(c *Context) { c.Text(200, "posts") }).Name("posts")
+	api := router.Group("/api")
+	api.Put("/items/:id", func(c *Context) { c.Text(200, "item") })
+
+	assert.False(t, router.Unregister("GET", "/users/:name"))
+	assert.False(t, router.Unregister("POST", "/users/:id"))
+
+	assert.True(t, router.Unregister("GET", "/users/:id/"))
+	assert.Equal(t, 404, performRequest(router, "GET", "/users/1").Code)
+	_, err := router.URL("user", "id", 1)
+	assert.Error(t, err)
+
+	// the name is released once all the routes of the endpoint are removed
+	assert.True(t, router.Unregister("GET", "/posts(/:page)?"))
+	assert.Equal(t, 404, performRequest(router, "GET", "/posts").Code)
+	assert.Equal(t, 404, performRequest(router, "GET", "/posts/2").Code)
+	assert.Equal(t, "posts", performRequest(router, "POST", "/posts/2").Body.String())
+	url, err := router.URL("posts", "page", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "/posts/2", url)
+
+	assert.True(t, api.Unregister("PUT", "/items/:id"))
+	assert.True(t, router.Unregister("POST", "/posts(/:page)?"))
+	_, err = router.URL("posts")
+	assert.Error(t, err)
+	assert.Empty(t, router.Routes())
+
+	for method, root := range router.trees() {
+		assert.True(t, root.empty(), method)
+	}
+
+	// the patterns of the removed routes do not conflict anymore
+	router.ConflictMode = ConflictStrict
+	assert.NotPanics(t, func() {
+		router.Get("/users/:name", func(c *Context) {}).Name("user")
+	})
+}
+
+func TestUnregisterTree(t *testing.T) {
+	paths := []string{
+		"/contact",
+		"/cart",
+		"/carts/:id",
+		"/carts/:id/items/*path",
+		"/carts/(:id[0-9]+)/total",
+		"/static/css",
+	}
+
+	// removing a route leaves the same tree as if it had never been inserted
+	for i, removed := range paths {
+		router := NewRouter()
+		expected := NewRouter()
+		for j, path := range paths {
+			router.Get(path, func() {})
+			if j != i {
+				expected.Get(path, func() {})
+			}
+		}
+
+		assert.True(t, router.Unregister("GET", removed))
+		assert.Equal(t, treeString(expected.trees()["GET"]), treeString(router.trees()["GET"]), removed)
+	}
+}
+
+func treeString(n *node) string {
+	s := fmt.Sprintf("%d %q %q %q %v [", n.kind, n.segment, n.indices, n.path, n.route != nil)
+	for _, c := range n.children() {
+		s += treeString(c) + " "
+	}
+	return s + "]"
+}
+
+func TestUpdate(t *testing.T) {
+	router := New()
+	router.Get("/users", func(c *Context) { c.Text(200, "users") })
+	api := router.Host("api.example.com")
+
+	router.Update(func() {
+		router.Get("/plugins/:name", func(c *Context) { c.Text(200, "%s", c.Param("name")) }).Name("plugin")
+		api.Get("/status", func(c *Context) { c.Text(200, "ok") })
+		router.Unregister("GET", "/users")
+
+		// the changes are not served until fn returns
+		assert.Equal(t, "users", performRequest(router, "GET", "/users").Body.String())
+		assert.Equal(t, 404, performRequest(router, "GET", "/plugins/x").Code)
+	})
+
+	assert.Equal(t, 404, performRequest(router, "GET", "/users").Code)
+	assert.Equal(t, "x", performRequest(router, "GET", "/plugins/x").Body.String())
+	url, err := router.URL("plugin", "name", "y")
+	assert.NoError(t, err)
+	assert.Equal(t, "/plugins/y", url)
+
+	req, _ := http.NewRequest("GET", "/status", nil)
+	req.Host = "api.example.com"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "ok", w.Body.String())
+}
+
+func TestUpdatePanics(t *testing.T) {
+	router := New()
+	router.ConflictMode = ConflictStrict
+	users := router.Get("/users/:id", func(c *Context) {})
+
+	assert.Panics(t, func() {
+		router.Update(func() {
+			router.Get("/plugins", func(c *Context) {})
+			router.Get("/users/:name", func(c *Context) {})
+		})
+	})
+	assert.Equal(t, 404, performRequest(router, "GET", "/plugins").Code)
+	assert.Len(t, router.Routes(), 1)
+
+	// the endpoints being served can not be changed
+	assert.Panics(t, func() {
+		router.Update(func() { users.Name("user") })
+	})
+	assert.Panics(t, func() {
+		router.Update(func() { users.Tag("admin") })
+	})
+	assert.Empty(t, router.Routes()[0].Name)
+
+	// the router can still be updated
+	router.Update(func() {
+		router.Get("/plugins", func(c *Context) {}).Name("plugins")
+	})
+	assert.Equal(t, 200, performRequest(router, "GET", "/plugins").Code)
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+	router := New()
+	router.Get("/stable/:id", func(c *Context) { c.Text(200, "%s", c.Param("id")) })
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				w := performRequest(router, "GET", "/stable/1")
+				if w.Code != 200 || w.Body.String() != "1" {
+					t.Error("unexpected response", w.Code, w.Body.String())
+					return
+				}
+				performRequest(router, "GET", "/plugins/1")
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		router.Update(func() {
+			router.Get(fmt.Sprintf("/plugins/%d", i), func(c *Context) {})
+			if i > 0 {
+				router.Unregister("GET", fmt.Sprintf("/plugins/%d", i-1))
+			}
+		})
+	}
+	close(done)
+	wg.Wait()
+
+	assert.Len(t, router.Routes(), 2)
+}
@@ -0,0 +1,247 @@
+package mel
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+var errHijackTimeout = errors.New("Hijack is not supported under the timeout middleware")
+
+// TimeoutConfig configures the Timeout middleware.
+type TimeoutConfig struct {
+	// Timeout bounds the execution of the handlers after the middleware.
+	Timeout time.Duration
+
+	// Status is the status code written on timeout, 503 by default.
+	// Usually it is either http.StatusServiceUnavailable or http.StatusGatewayTimeout.
+	Status int
+
+	// Response writes the response on timeout, in place of the default plain text body.
+	// It is called with the request's Context, after the chain has been aborted
+	// and the status has been set.
+	Response Handler
+}
+
+// Timeout returns a middleware that bounds the execution time of the handlers after it to d.
+// It can be attached globally with Mel.Use, to a RoutesGroup, or to a single route.
+func Timeout(d time.Duration) Handler {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: d})
+}
+
+// TimeoutWithConfig returns a Timeout middleware with the given config.
+//
+// The pending handlers run in another goroutine on a copy of the Context, and their response
+// is buffered. If they finish in time, the buffered response is written to the client.
+// Otherwise, the chain is aborted, the timeout response is written, and http.ErrHandlerTimeout is
+// pushed to Context.Errors. Later writes from the handlers are discarded and return
+// http.ErrHandlerTimeout, and the Context can be recycled safely while they are still running.
+// The request's context is canceled on timeout, so handlers should watch Context.Done().
+// A panic of the handlers is re-raised by the middleware, with the stack of their goroutine.
+func TimeoutWithConfig(config TimeoutConfig) Handler {
+	check(config.Timeout > 0, "Timeout must be positive")
+	if config.Status == 0 {
+		config.Status = http.StatusServiceUnavailable
+	}
+
+	return func(c *Context) {
+		// the handlers before the middleware get back the request, whose context is not canceled
+		req := c.Request
+		cancel := c.WithTimeout(config.Timeout)
+		defer func() {
+			cancel()
+			c.Request = req
+		}()
+
+		tw := newTimeoutWriter(c.Writer.Header())
+		cp := c.Copy()
+		cp.Writer = tw
+		cp.handlers = c.handlers
+		cp.index = c.index
+
+		finish := make(chan struct{})
+		panicChan := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					if p != http.ErrAbortHandler {
+						p = &timeoutPanic{value: p, stack: debug.Stack()}
+					}
+					panicChan <- p
+				}
+			}()
+			cp.Next()
+			close(finish)
+		}()
+
+		select {
+		case p := <-panicChan:
+			tw.timeout()
+			panic(p)
+
+		case <-finish:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			c.Params = cp.Params
+			c.Keys = cp.Keys
+			c.Errors = cp.Errors
+			c.index = cp.index
+
+			header := c.Writer.Header()
+			for k := range header {
+				delete(header, k)
+			}
+			for k, v := range tw.header {
+				header[k] = v
+			}
+			if tw.status != 0 {
+				c.Writer.Status(tw.status)
+			}
+			if tw.written {
+				c.Writer.WriteHeader(tw.status)
+			}
+			if tw.buf.Len() > 0 {
+				c.Writer.Write(tw.buf.Bytes())
+			}
+
+		case <-c.Done():
+			tw.timeout()
+			c.Abort()
+			c.Error(http.ErrHandlerTimeout)
+			c.Status(config.Status)
+			if config.Response != nil {
+				config.Response(c)
+			}
+			if !c.Writer.Written() {
+				c.Text(config.Status, "%d %s", config.Status, http.StatusText(config.Status))
+			}
+		}
+	}
+}
+
+// timeoutPanic is the panic re-raised by the Timeout middleware when a handler panics,
+// with the stack of the goroutine the handler ran in.
+type timeoutPanic struct {
+	value interface{}
+	stack []byte
+}
+
+func (p *timeoutPanic) Error() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
+}
+
+// Unwrap returns the panic value of the handler if it is an error.
+func (p *timeoutPanic) Unwrap() error {
+	err, _ := p.value.(error)
+	return err
+}
+
+// timeoutWriter buffers the response of the handlers running under the Timeout middleware.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	status   int
+	written  bool
+	timedOut bool
+	closed   chan bool
+}
+
+var _ ResponseWriter = &timeoutWriter{}
+
+func newTimeoutWriter(header http.Header) *timeoutWriter {
+	tw := &timeoutWriter{
+		header: make(http.Header, len(header)),
+		closed: make(chan bool, 1),
+	}
+	for k, v := range header {
+		tw.header[k] = v
+	}
+	return tw
+}
+
+func (tw *timeoutWriter) timeout() {
+	tw.mu.Lock()
+	tw.timedOut = true
+	tw.mu.Unlock()
+	tw.closed <- true
+}
+
+func (tw *timeoutWriter) Reset(writer http.ResponseWriter) {}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.written {
+		return
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	tw.status = status
+	tw.written = true
+}
+
+func (tw *timeoutWriter) Write(data []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.written {
+		if tw.status == 0 {
+			tw.status = http.StatusOK
+		}
+		tw.written = true
+	}
+	return tw.buf.Write(data)
+}
+
+func (tw *timeoutWriter) WriteString(s string) (int, error) {
+	return tw.Write([]byte(s))
+}
+
+func (tw *timeoutWriter) Status(status ...int) int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if len(status) > 0 && !tw.timedOut {
+		tw.status = status[0]
+	}
+	return tw.status
+}
+
+func (tw *timeoutWriter) Size() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.buf.Len()
+}
+
+func (tw *timeoutWriter) Written() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.written
+}
+
+// Flush does nothing, the response is written once the handlers have finished.
+func (tw *timeoutWriter) Flush() {}
+
+// Hijack always fails, the connection belongs to the timeout middleware.
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errHijackTimeout
+}
+
+// CloseNotify returns a channel which receives a value on timeout.
+func (tw *timeoutWriter) CloseNotify() <-chan bool {
+	return tw.closed
+}
@@ -0,0 +1,120 @@
+package mel
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutInTime(t *testing.T) {
+	router := New()
+	router.Use(func(c *Context) {
+		c.Header("X-Global", "yes")
+		c.Next()
+	})
+	router.Get("/fast", Timeout(time.Second), func(c *Context) {
+		c.Set("user", "manu")
+		c.Header("X-Handler", "yes")
+		c.Text(201, "done %s", c.Param("missing", "ok"))
+	})
+
+	w := performRequest(router, "GET", "/fast")
+	assert.Equal(t, 201, w.Code)
+	assert.Equal(t, "done ok", w.Body.String())
+	assert.Equal(t, "yes", w.HeaderMap.Get("X-Global"))
+	assert.Equal(t, "yes", w.HeaderMap.Get("X-Handler"))
+}
+
+func TestTimeoutExpired(t *testing.T) {
+	router := New()
+	var errs Errors
+	router.Use(func(c *Context) {
+		c.Next()
+		errs = c.Errors
+	})
+
+	written := make(chan error, 1)
+	router.Get("/slow", Timeout(10*time.Millisecond), func(c *Context) {
+		<-c.Done()
+		time.Sleep(10 * time.Millisecond)
+		_, err := c.Writer.Write([]byte("too late"))
+		written <- err
+	})
+
+	w := performRequest(router, "GET", "/slow")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "503 Service Unavailable", w.Body.String())
+	assert.Len(t, errs, 1)
+	assert.Equal(t, http.ErrHandlerTimeout, errs.Last().Err)
+
+	assert.Equal(t, http.ErrHandlerTimeout, <-written)
+	assert.Equal(t, "503 Service Unavailable", w.Body.String())
+}
+
+func TestTimeoutWithConfig(t *testing.T) {
+	router := New()
+	group := router.Group("/api", TimeoutWithConfig(TimeoutConfig{
+		Timeout: 10 * time.Millisecond,
+		Status:  http.StatusGatewayTimeout,
+		Response: func(c *Context) {
+			c.JSON(c.Writer.Status(), Map{"error": "timeout"})
+		},
+	}))
+	group.Get("/slow", func(c *Context) { time.Sleep(50 * time.Millisecond) })
+
+	w := performRequest(router, "GET", "/api/slow")
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Equal(t, "{\"error\":\"timeout\"}\n", w.Body.String())
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	router := New()
+	router.Get("/panic", Timeout(time.Second), func(c *Context) { panic("oops") })
+
+	defer func() {
+		p := recover()
+		if assert.IsType(t, &timeoutPanic{}, p) {
+			assert.Equal(t, "oops", p.(*timeoutPanic).value)
+			// the stack of the handler is kept
+			assert.Contains(t, p.(error).Error(), "timeout_test.go")
+		}
+	}()
+	performRequest(router, "GET", "/panic")
+}
+
+func TestTimeoutRestoresRequest(t *testing.T) {
+	router := New()
+	var err error
+	var deadline bool
+	router.Use(func(c *Context) {
+		c.Next()
+		err = c.Err()
+		_, deadline = c.Deadline()
+	})
+	router.Get("/fast", Timeout(time.Second), func(c *Context) { c.Text(200, "done") })
+	router.Get("/slow", Timeout(10*time.Millisecond), func(c *Context) { <-c.Done() })
+
+	for _, path := range []string{"/fast", "/slow"} {
+		err, deadline = context.Canceled, true
+		performRequest(router, "GET", path)
+		assert.Nil(t, err, path)
+		assert.False(t, deadline, path)
+	}
+}
+
+func TestTimeoutAbort(t *testing.T) {
+	router := New()
+	var called bool
+	router.Get("/abort", Timeout(time.Second), Handler(func(c *Context) {
+		c.AbortWithStatus(401)
+	}), func(c *Context) {
+		called = true
+	})
+
+	w := performRequest(router, "GET", "/abort")
+	assert.Equal(t, 401, w.Code)
+	assert.False(t, called)
+}
@@ -0,0 +1,429 @@
+package mel
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The routes of a method are stored in a compressed radix tree. The static parts of
+// the patterns are merged by their common prefixes, and the params are the children
+// of the static node they follow.
+//
+// A request path is matched against the tree depth first, with the following precedence
+// at every node, whatever the order the routes were registered in:
+//
+//  1. the static child, e.g. "/users/new" before "/users/:id";
+//  2. the params with a regexp or a constraint, e.g. "(:id[0-9]+)" or ":id<int>",
+//     in the order they were registered;
+//  3. the named params, e.g. ":name", in the order they were registered;
+//  4. the wildcard params, e.g. "*filepath", in the order they were registered.
+//
+// If a child does not lead to a route, the next one is tried. A named or regexp param
+// matches a non-empty part of a single path segment, the shortest one which leads to a route.
+// A wildcard param matches the longest part of the path which leads to a route through
+// the static part after it, possibly empty, or else the whole non-empty rest of the path.
+
+type nodeKind byte
+
+const (
+	staticNode nodeKind = iota
+	namedNode
+	anyNode
+	regexNode
+)
+
+type node struct {
+	kind    nodeKind
+	segment string         // static path part, or param name, e.g. ":id"
+	regexp  *regexp.Regexp // non-null when kind is regexNode, unless the param has a constraint
+
+	constraint string     // the name of the param constraint, e.g. "int" for ":id<int>"
+	validate   Constraint // non-null when the param has a constraint
+
+	indices string  // the first bytes of the segments of the static children
+	statics []*node // the static children
+	params  []*node // the param children, sorted by precedence
+
+	path  string // the entire path, only presents in the "leaf" node
+	route *Route
+}
+
+type nodes []*node
+
+func (n *node) equal(o *node) bool {
+	if n.kind != o.kind || n.segment != o.segment || n.constraint != o.constraint {
+		return false
+	}
+	if n.regexp == nil || o.regexp == nil {
+		return n.regexp == o.regexp
+	}
+	return n.regexp.String() == o.regexp.String()
+}
+
+// matchValue reports whether value satisfies the regexp or the constraint of a regexNode.
+func (n *node) matchValue(value string) bool {
+	if n.validate != nil {
+		return n.validate(value)
+	}
+	return n.regexp.MatchString(value)
+}
+
+// precedence ranks the param children of a node, the lowest first.
+func (n *node) precedence() int {
+	switch n.kind {
+	case regexNode:
+		return 0
+	case namedNode:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (n *node) empty() bool {
+	return n.route == nil && len(n.statics) == 0 && len(n.params) == 0
+}
+
+// insert inserts the parsed segments of a pattern under n, and returns the leaf node.
+func (n *node) insert(segments []*node) *node {
+	for _, seg := range mergeStatics(segments) {
+		if seg.kind != staticNode {
+			n = n.addParam(seg)
+		} else {
+			n = n.addStatic(seg.segment)
+		}
+	}
+	return n
+}
+
+// mergeStatics merges the consecutive static segments of a parsed pattern, e.g. "/static" and "/css".
+func mergeStatics(segments []*node) []*node {
+	merged := make([]*node, 0, len(segments))
+	for i := 0; i < len(segments); i++ {
+		seg := segments[i]
+		if seg.kind == staticNode {
+			s := seg.segment
+			for ; i+1 < len(segments) && segments[i+1].kind == staticNode; i++ {
+				s += segments[i+1].segment
+			}
+			seg = &node{kind: staticNode, segment: s}
+		}
+		merged = append(merged, seg)
+	}
+	return merged
+}
+
+// find returns the node of the parsed segments of a pattern under n, or nil if there is none.
+func (n *node) find(segments []*node) *node {
+	for _, seg := range mergeStatics(segments) {
+		if seg.kind != staticNode {
+			var next *node
+			for _, c := range n.params {
+				if c.equal(seg) {
+					next = c
+					break
+				}
+			}
+			if next == nil {
+				return nil
+			}
+			n = next
+			continue
+		}
+
+		// the static segment may span several nodes
+		for s := seg.segment; len(s) > 0; s = s[len(n.segment):] {
+			i := strings.IndexByte(n.indices, s[0])
+			if i < 0 || !strings.HasPrefix(s, n.statics[i].segment) {
+				return nil
+			}
+			n = n.statics[i]
+		}
+	}
+	return n
+}
+
+// remove removes the route of the parsed segments of a pattern under n, and returns it,
+// or nil if there is none. The nodes left without routes are removed, and the static
+// nodes are merged again, so that the tree is the same as if the route had never been inserted.
+func (n *node) remove(segments []*node) *Route {
+	return n.removeMerged(mergeStatics(segments))
+}
+
+func (n *node) removeMerged(segments []*node) *Route {
+	if len(segments) == 0 {
+		route := n.route
+		n.route, n.path = nil, ""
+		return route
+	}
+
+	seg := segments[0]
+	if seg.kind != staticNode {
+		for i, c := range n.params {
+			if c.equal(seg) {
+				route := c.removeMerged(segments[1:])
+				if route != nil && c.empty() {
+					n.params = append(n.params[:i:i], n.params[i+1:]...)
+				}
+				return route
+			}
+		}
+		return nil
+	}
+
+	s := seg.segment
+	if len(s) == 0 {
+		return n.removeMerged(segments[1:])
+	}
+	i := strings.IndexByte(n.indices, s[0])
+	if i < 0 || !strings.HasPrefix(s, n.statics[i].segment) {
+		return nil
+	}
+
+	// the static segment may span several nodes
+	c := n.statics[i]
+	rest := segments[1:]
+	if len(s) > len(c.segment) {
+		rest = append([]*node{{kind: staticNode, segment: s[len(c.segment):]}}, rest...)
+	}
+	route := c.removeMerged(rest)
+	if route == nil {
+		return nil
+	}
+	if c.empty() {
+		n.indices = n.indices[:i] + n.indices[i+1:]
+		n.statics = append(n.statics[:i:i], n.statics[i+1:]...)
+	} else if c.route == nil && len(c.params) == 0 && len(c.statics) == 1 {
+		// merge c with its only child, e.g. "/c" and "art" when "/contact" is removed
+		segment := c.segment
+		*c = *c.statics[0]
+		c.segment = segment + c.segment
+	}
+	return route
+}
+
+// clone returns a copy of the tree under n, which can be modified without affecting n.
+// The routes are shared.
+func (n *node) clone() *node {
+	c := *n
+	if n.statics != nil {
+		c.statics = make([]*node, len(n.statics))
+		for i, child := range n.statics {
+			c.statics[i] = child.clone()
+		}
+	}
+	if n.params != nil {
+		c.params = make([]*node, len(n.params))
+		for i, child := range n.params {
+			c.params[i] = child.clone()
+		}
+	}
+	return &c
+}
+
+// addStatic returns the node whose path ends with s under n, splitting the nodes if needed.
+func (n *node) addStatic(s string) *node {
+	for len(s) > 0 {
+		i := strings.IndexByte(n.indices, s[0])
+		if i < 0 {
+			child := &node{kind: staticNode, segment: s}
+			n.indices += s[:1]
+			n.statics = append(n.statics, child)
+			return child
+		}
+
+		child := n.statics[i]
+		l := commonPrefix(child.segment, s)
+		if l < len(child.segment) {
+			split := *child
+			split.segment = child.segment[l:]
+			*child = node{
+				kind:    staticNode,
+				segment: child.segment[:l],
+				indices: split.segment[:1],
+				statics: []*node{&split},
+			}
+		}
+		s = s[l:]
+		n = child
+	}
+	return n
+}
+
+// addParam returns the child of n equal to the param p, adding p if there is none.
+func (n *node) addParam(p *node) *node {
+	for _, c := range n.params {
+		if c.equal(p) {
+			return c
+		}
+	}
+
+	child := &node{
+		kind:       p.kind,
+		segment:    p.segment,
+		regexp:     p.regexp,
+		constraint: p.constraint,
+		validate:   p.validate,
+	}
+	i := len(n.params)
+	for i > 0 && n.params[i-1].precedence() > child.precedence() {
+		i--
+	}
+	n.params = append(n.params, nil)
+	copy(n.params[i+1:], n.params[i:])
+	n.params[i] = child
+	return child
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// lookup returns the leaf node of the route matching path, whose nodes up to n are matched,
+// and appends the params of the route to params.
+//
+// If fold is not nil, the static parts of the patterns are matched ignoring the case of
+// the ASCII letters, and they are copied into fold, a copy of the whole path, in the casing
+// of the route, so that fold is the canonical path of the route if it matches.
+func (n *node) lookup(path string, params Params, fold []byte) (*node, Params) {
+	if len(path) == 0 {
+		if n.route != nil {
+			return n, params
+		}
+		return nil, nil
+	}
+
+	if fold == nil {
+		if i := strings.IndexByte(n.indices, path[0]); i > -1 {
+			c := n.statics[i]
+			if strings.HasPrefix(path, c.segment) {
+				if leaf, ps := c.lookup(path[len(c.segment):], params, nil); leaf != nil {
+					return leaf, ps
+				}
+			}
+		}
+	} else {
+		for _, c := range n.statics {
+			if hasPrefixFold(path, c.segment) {
+				if leaf, ps := c.lookup(path[len(c.segment):], params, fold); leaf != nil {
+					copy(fold[len(fold)-len(path):], c.segment)
+					return leaf, ps
+				}
+			}
+		}
+	}
+
+	for _, c := range n.params {
+		if leaf, ps := c.lookupParam(path, params, fold); leaf != nil {
+			return leaf, ps
+		}
+	}
+	return nil, nil
+}
+
+// lookupParam tries the possible values of the param n at the beginning of path.
+func (n *node) lookupParam(path string, params Params, fold []byte) (*node, Params) {
+	if n.kind == anyNode {
+		// the longest value followed by a static child, possibly empty
+		for end := len(path) - 1; end >= 0; end-- {
+			if !n.hasIndex(path[end], fold != nil) {
+				continue
+			}
+			if leaf, ps := n.lookup(path[end:], append(params, Param{n.segment, path[:end]}), fold); leaf != nil {
+				return leaf, ps
+			}
+		}
+		if n.route != nil {
+			return n, append(params, Param{n.segment, path})
+		}
+		return nil, nil
+	}
+
+	// the shortest value in the segment
+	end := strings.IndexByte(path, '/')
+	if end < 0 {
+		end = len(path)
+	}
+	for i := 1; i <= end; i++ {
+		if i < len(path) && !n.hasIndex(path[i], fold != nil) {
+			continue
+		}
+		if n.kind == regexNode && !n.matchValue(path[:i]) {
+			continue
+		}
+		if leaf, ps := n.lookup(path[i:], append(params, Param{n.segment, path[:i]}), fold); leaf != nil {
+			return leaf, ps
+		}
+	}
+	return nil, nil
+}
+
+// hasIndex reports whether a static child of n may begin with c, ignoring its case if fold is true.
+func (n *node) hasIndex(c byte, fold bool) bool {
+	if !fold {
+		return strings.IndexByte(n.indices, c) > -1
+	}
+	for i := 0; i < len(n.indices); i++ {
+		if lowerASCII(n.indices[i]) == lowerASCII(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPrefixFold is like strings.HasPrefix, but it ignores the case of the ASCII letters.
+func hasPrefixFold(s, prefix string) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if lowerASCII(s[i]) != lowerASCII(prefix[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func lowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func printNodes(i int, nodes []*node) {
+	for _, n := range nodes {
+		for j := 0; j < i; j++ {
+			fmt.Print("  ")
+		}
+		if i > 1 {
+			fmt.Print("┗", "  ")
+		}
+
+		fmt.Print(n.segment)
+		if n.regexp != nil {
+			fmt.Print(n.regexp)
+		}
+		if len(n.constraint) > 0 {
+			fmt.Print("<", n.constraint, ">")
+		}
+		if len(n.path) != 0 {
+			fmt.Printf("  path[ %s ]", n.path)
+		}
+		if n.route != nil {
+			fmt.Printf("  func[ %p ]", n.route.method.Interface())
+		}
+		fmt.Println()
+		printNodes(i+1, n.children())
+	}
+}
+
+// children returns the children of n, in the order of precedence.
+func (n *node) children() []*node {
+	return append(append([]*node(nil), n.statics...), n.params...)
+}
@@ -0,0 +1,146 @@
+package mel
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTreePrecedence(t *testing.T) {
+	paths := []string{
+		"/*path",
+		"/users/*path",
+		"/users/:name",
+		"/users/(:id[0-9]+)",
+		"/users/new",
+		"/users/:name/posts",
+		"/users/:name/*path",
+	}
+	expects := []MatchResult{
+		{"/users/new", true, Params{}},
+		{"/users/42", true, Params{{":id", "42"}}},
+		{"/users/tom", true, Params{{":name", "tom"}}},
+		{"/users/42/posts", true, Params{{":name", "42"}}},
+		{"/users/tom/posts/1", true, Params{{":name", "tom"}, {"*path", "posts/1"}}},
+		{"/users/tom/1", true, Params{{":name", "tom"}, {"*path", "1"}}},
+		{"/users/", true, Params{{"*path", "users/"}}},
+		{"/about", true, Params{{"*path", "about"}}},
+	}
+
+	// the precedence does not depend on the registration order
+	for i := range paths {
+		router := NewRouter()
+		for j := range paths {
+			router.Register("GET", paths[(i+j)%len(paths)], func() {})
+		}
+		for _, expect := range expects {
+			handler, params, _ := router.Match("GET", expect.path)
+			assert.NotNil(t, handler, expect.path)
+			assert.Equal(t, expect.params, params, expect.path)
+		}
+	}
+}
+
+func TestTreeCompression(t *testing.T) {
+	router := NewRouter()
+	router.Register("GET", "/contact", func() {})
+	router.Register("GET", "/cart", func() {})
+	router.Register("GET", "/carts/:id", func() {})
+
+	root := router.trees()["GET"]
+	assert.Len(t, root.children(), 1)
+	assert.Equal(t, "/c", root.children()[0].segment)
+
+	for _, path := range []string{"/contact", "/cart", "/carts/1"} {
+		handler, _, _ := router.Match("GET", path)
+		assert.NotNil(t, handler, path)
+	}
+	for _, path := range []string{"/c", "/car", "/carts", "/contacts"} {
+		handler, _, _ := router.Match("GET", path)
+		assert.Nil(t, handler, path)
+	}
+}
+
+// newLargeRouter registers 5000 routes: static, named, regexp and wildcard ones
+// spread over 50 resources.
+func newLargeRouter() *Router {
+	router := NewRouter()
+	for i := 0; i < 50; i++ {
+		resource := fmt.Sprintf("/api/v%d/resource%d", i%3+1, i)
+		for j := 0; j < 25; j++ {
+			router.Register("GET", fmt.Sprintf("%s/static%d", resource, j), func() {})
+			router.Register("GET", fmt.Sprintf("%s/:id/child%d", resource, j), func() {})
+			router.Register("GET", fmt.Sprintf("%s/(:num[0-9]+)/item%d", resource, j), func() {})
+			router.Register("GET", fmt.Sprintf("%s/files%d/*path", resource, j), func() {})
+		}
+	}
+	return router
+}
+
+func TestLargeRouter(t *testing.T) {
+	router := newLargeRouter()
+	assert.Len(t, router.Routes(), 5000)
+
+	handler, params, _ := router.Match("GET", "/api/v3/resource47/static24")
+	assert.NotNil(t, handler)
+	assert.Empty(t, params)
+
+	handler, params, _ = router.Match("GET", "/api/v3/resource47/tom/child24")
+	assert.NotNil(t, handler)
+	assert.Equal(t, Params{{":id", "tom"}}, params)
+
+	handler, params, _ = router.Match("GET", "/api/v3/resource47/42/item3")
+	assert.NotNil(t, handler)
+	assert.Equal(t, Params{{":num", "42"}}, params)
+
+	handler, params, _ = router.Match("GET", "/api/v3/resource47/files7/a/b.txt")
+	assert.NotNil(t, handler)
+	assert.Equal(t, Params{{"*path", "a/b.txt"}}, params)
+
+	handler, _, _ = router.Match("GET", "/api/v3/resource47/tom/item3")
+	assert.Nil(t, handler)
+}
+
+func benchmarkLargeRouter(b *testing.B, path string) {
+	router := newLargeRouter()
+	params := make(Params, 0, 8)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.match("GET", path, params[:0])
+	}
+}
+
+func BenchmarkLargeRouterStatic(b *testing.B) {
+	benchmarkLargeRouter(b, "/api/v3/resource47/static24")
+}
+
+func BenchmarkLargeRouterParam(b *testing.B) {
+	benchmarkLargeRouter(b, "/api/v3/resource47/tom/child24")
+}
+
+func BenchmarkLargeRouterRegexp(b *testing.B) {
+	benchmarkLargeRouter(b, "/api/v3/resource47/42/item24")
+}
+
+func BenchmarkLargeRouterWildcard(b *testing.B) {
+	benchmarkLargeRouter(b, "/api/v3/resource47/files24/a/b/c.txt")
+}
+
+func BenchmarkLargeRouterNotFound(b *testing.B) {
+	benchmarkLargeRouter(b, "/api/v3/resource47/tom/missing")
+}
+
+func BenchmarkLargeRouterServeHTTP(b *testing.B) {
+	router := New()
+	router.Router = newLargeRouter()
+	w := &discardWriter{header: http.Header{}}
+	req, _ := http.NewRequest("GET", "/api/v3/resource47/tom/child24", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(w, req)
+	}
+}
@@ -0,0 +1,185 @@
+package mel
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/ridewindx/mel/binding"
+)
+
+// StatusCoder is implemented by the errors which carry the status code of the response.
+// A typed handler answers a returned error with its status code, or else with 500.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+var (
+	contextType = reflect.TypeOf(&Context{})
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// typedHandler describes a handler with a typed input and typed return values, e.g.
+// func(*Context, *CreateUserReq) (*User, error).
+type typedHandler struct {
+	req       reflect.Type // the struct type of the request, nil if the handler has no request
+	hasResult bool
+	hasError  bool
+}
+
+// newTypedHandler returns the typedHandler of a function type, or nil if it is not one of:
+//
+//	func(*Context, *Req)
+//	func(*Context[, *Req]) error
+//	func(*Context[, *Req]) (Resp, error)
+//
+// where Req is a struct type.
+func newTypedHandler(t reflect.Type) *typedHandler {
+	if t.NumIn() == 0 || t.NumIn() > 2 || t.In(0) != contextType {
+		return nil
+	}
+
+	h := &typedHandler{}
+	if t.NumIn() == 2 {
+		if t.In(1).Kind() != reflect.Ptr || t.In(1).Elem().Kind() != reflect.Struct {
+			return nil
+		}
+		h.req = t.In(1).Elem()
+	}
+
+	switch t.NumOut() {
+	case 0:
+		if h.req == nil {
+			return nil // a plain func(*Context)
+		}
+	case 1:
+		if t.Out(0) != errorType {
+			return nil
+		}
+		h.hasError = true
+	case 2:
+		if t.Out(1) != errorType {
+			return nil
+		}
+		h.hasResult, h.hasError = true, true
+	default:
+		return nil
+	}
+	return h
+}
+
+// call binds and validates the request, calls the handler, and renders its result or its error.
+func (h *typedHandler) call(c *Context, fn reflect.Value) {
+	args := []reflect.Value{reflect.ValueOf(c)}
+	if h.req != nil {
+		req := reflect.New(h.req)
+		if code, err := bindTyped(c, req.Interface()); err != nil {
+			c.Error(err).Type = ErrorTypeBind
+			renderTypedError(c, code, err)
+			return
+		}
+		args = append(args, req)
+	}
+
+	out := fn.Call(args)
+	if c.Writer.Written() {
+		return
+	}
+
+	if h.hasError {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			code := http.StatusInternalServerError
+			if sc, ok := err.(StatusCoder); ok {
+				code = sc.StatusCode()
+			}
+			renderTypedError(c, code, c.Error(err))
+			return
+		}
+	}
+
+	status := c.Writer.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if !h.hasResult || isNil(out[0]) {
+		if status == http.StatusOK {
+			status = http.StatusNoContent
+		}
+		c.Writer.WriteHeader(status)
+		return
+	}
+	renderNegotiated(c, status, out[0].Interface())
+}
+
+var errUnsupportedContentType = errors.New("Unsupported content type")
+
+// bindTyped binds the request to obj, from the query string if the request has no body,
+// or else from the body according to its Content-Type.
+func bindTyped(c *Context, obj interface{}) (int, error) {
+	var b binding.Binding = binding.Form
+	switch c.Request.Method {
+	case "GET", "HEAD", "DELETE":
+	default:
+		if c.Request.ContentLength == 0 && len(c.ContentType()) == 0 {
+			break
+		}
+		switch c.ContentType() {
+		case binding.MIMEJSON:
+			b = binding.JSON
+		case binding.MIMEXML, binding.MIMEXMLText:
+			b = binding.XML
+		case binding.MIMEPROTOBUF:
+			b = binding.ProtoBuf
+		case binding.MIMEPOSTForm:
+			b = binding.Form
+		case binding.MIMEMultipartPOSTForm:
+			// the multipart forms are parsed with the limits of the app, see Context.BindWith
+			if err := c.parseMultipartForm(); err != nil {
+				if e, ok := err.(*UploadError); ok {
+					return e.Status, err
+				}
+				return http.StatusBadRequest, err
+			}
+			b = binding.Form
+		default:
+			return http.StatusUnsupportedMediaType,
+				fmt.Errorf("%s: %q", errUnsupportedContentType, c.ContentType())
+		}
+	}
+
+	if err := b.Bind(c.Request, obj); err != nil {
+		return http.StatusBadRequest, err
+	}
+	return 0, nil
+}
+
+// renderTypedError answers with the status code, and with the error if it is public.
+func renderTypedError(c *Context, code int, err error) {
+	var obj interface{} = Object{"error": http.StatusText(code)}
+	if e, ok := err.(*Error); ok && e.IsType(ErrorTypePublic) {
+		obj = e.JSON()
+	}
+	renderNegotiated(c, code, obj)
+}
+
+// renderNegotiated renders obj in the format the client prefers among JSON, XML and YAML,
+// JSON by default.
+func renderNegotiated(c *Context, status int, obj interface{}) {
+	err := c.Negotiate(status, Negotiation{
+		Offered:  []string{binding.MIMEJSON, binding.MIMEXML, binding.MIMEXMLText, binding.MIMEYAML},
+		Fallback: binding.MIMEJSON,
+		Data:     obj,
+	})
+	if err != nil {
+		c.Error(err).Type = ErrorTypeRender
+	}
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
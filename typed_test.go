@@ -0,0 +1,120 @@
+package mel
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type createUserReq struct {
+	Name string `json:"name" form:"name" binding:"required"`
+	Age  int    `json:"age" form:"age"`
+}
+
+type user struct {
+	ID   int    `json:"id" xml:"id"`
+	Name string `json:"name" xml:"name"`
+}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string   { return "user not found" }
+func (notFoundError) StatusCode() int { return 404 }
+
+func performTypedRequest(r http.Handler, method, path, contentType, accept, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	if len(contentType) > 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if len(accept) > 0 {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestTypedHandler(t *testing.T) {
+	router := New()
+	router.Post("/users", func(c *Context, req *createUserReq) (*user, error) {
+		c.Status(201)
+		return &user{ID: 1, Name: req.Name}, nil
+	})
+	router.Get("/users", func(c *Context, req *createUserReq) (*user, error) {
+		return &user{ID: req.Age, Name: req.Name}, nil
+	})
+
+	w := performTypedRequest(router, "POST", "/users", "application/json", "", `{"name":"manu"}`)
+	assert.Equal(t, 201, w.Code)
+	assert.Equal(t, `{"id":1,"name":"manu"}`+"\n", w.Body.String())
+	assert.Equal(t, "Accept", w.HeaderMap.Get("Vary"))
+
+	w = performTypedRequest(router, "POST", "/users", "application/x-www-form-urlencoded", "application/xml", "name=manu")
+	assert.Equal(t, 201, w.Code)
+	assert.Equal(t, `<user><id>1</id><name>manu</name></user>`, w.Body.String())
+
+	w = performTypedRequest(router, "GET", "/users?name=manu&age=30", "", "", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, `{"id":30,"name":"manu"}`+"\n", w.Body.String())
+
+	// validation error
+	w = performTypedRequest(router, "POST", "/users", "application/json", "", `{"age":30}`)
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, `{"error":"Bad Request"}`+"\n", w.Body.String())
+
+	w = performTypedRequest(router, "POST", "/users", "text/csv", "", `name`)
+	assert.Equal(t, 415, w.Code)
+}
+
+func TestTypedHandlerErrors(t *testing.T) {
+	router := New()
+	var errs Errors
+	router.Use(func(c *Context) {
+		c.Next()
+		errs = c.Errors
+	})
+	router.Get("/missing", func(c *Context) (*user, error) {
+		return nil, notFoundError{}
+	})
+	router.Get("/failing", func(c *Context) error {
+		return errors.New("database is down")
+	})
+	router.Get("/public", func(c *Context) (*user, error) {
+		return nil, &Error{Err: errors.New("quota exceeded"), Type: ErrorTypePublic}
+	})
+	router.Delete("/users/:id", func(c *Context) error {
+		return nil
+	})
+
+	w := performRequest(router, "GET", "/missing")
+	assert.Equal(t, 404, w.Code)
+	assert.Equal(t, `{"error":"Not Found"}`+"\n", w.Body.String())
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "user not found", errs[0].Error())
+
+	w = performRequest(router, "GET", "/failing")
+	assert.Equal(t, 500, w.Code)
+	assert.Equal(t, "database is down", errs.Last().Error())
+
+	w = performRequest(router, "GET", "/public")
+	assert.Equal(t, 500, w.Code)
+	assert.Equal(t, `{"error":"quota exceeded"}`+"\n", w.Body.String())
+
+	w = performRequest(router, "DELETE", "/users/42")
+	assert.Equal(t, 204, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestTypedHandlerInvalid(t *testing.T) {
+	router := New()
+	assert.Panics(t, func() {
+		router.Get("/", func(c *Context, name string) error { return nil })
+	})
+	assert.Panics(t, func() {
+		router.Get("/", func(c *Context, req createUserReq) error { return nil })
+	})
+}
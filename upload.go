@@ -0,0 +1,291 @@
+package mel
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/ridewindx/mel/binding"
+)
+
+// defaultMultipartMemory is the default value of Mel.MaxMultipartMemory.
+const defaultMultipartMemory = 32 << 20 // 32 MB
+
+// UploadLimits restricts the files uploaded in multipart forms, see Mel.Uploads.
+// The zero value allows any file.
+type UploadLimits struct {
+	MaxFiles     int      // the maximum number of files of a request, unlimited if 0
+	MaxFileSize  int64    // the maximum size of a file in bytes, unlimited if 0
+	AllowedTypes []string // the allowed media types of the files, e.g. "image/png" or "image/*", any type if empty
+
+	// MaxSize is the maximum size of the body of a multipart request in bytes, which is read no further.
+	// If 0, it is derived from MaxFiles and MaxFileSize when both are set, allowing for the values
+	// of the form as much as net/http, i.e. the memory limit of the app and 10 MB, and it is unlimited otherwise.
+	MaxSize int64
+}
+
+// partOverhead is the size allowed to the headers and the boundary of each part of a multipart request.
+const partOverhead = 4 << 10 // 4 KB
+
+// UploadError is the error of an upload which exceeds the limits of the app.
+// Its status is 413 Request Entity Too Large for too many or too large files, or a too large form,
+// and 415 Unsupported Media Type for a file whose type is not allowed.
+type UploadError struct {
+	Status   int
+	Filename string // the name of the rejected file, if any
+	msg      string
+}
+
+func (e *UploadError) Error() string {
+	if len(e.Filename) > 0 {
+		return fmt.Sprintf("%s: %q", e.msg, e.Filename)
+	}
+	return e.msg
+}
+
+var errFormTooLarge = &UploadError{Status: http.StatusRequestEntityTooLarge, msg: "Multipart form too large"}
+
+// maxSize returns the maximum size of the body of a multipart request, or 0 if unlimited, see MaxSize.
+func (l *UploadLimits) maxSize(maxMemory int64) int64 {
+	if l.MaxSize > 0 || l.MaxFiles <= 0 || l.MaxFileSize <= 0 {
+		return l.MaxSize
+	}
+	return int64(l.MaxFiles)*(l.MaxFileSize+partOverhead) + maxMemory + 10<<20
+}
+
+// check checks the files of a parsed multipart form against the limits.
+func (l *UploadLimits) check(form *multipart.Form) error {
+	var n int
+	for _, files := range form.File {
+		for _, file := range files {
+			n++
+			if err := l.checkFile(n, file.Filename, file.Header.Get("Content-Type"), file.Size); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkFile checks the n-th file of a request, with its size if it is known, against the limits.
+func (l *UploadLimits) checkFile(n int, filename, contentType string, size int64) error {
+	if l.MaxFiles > 0 && n > l.MaxFiles {
+		return &UploadError{
+			Status: http.StatusRequestEntityTooLarge,
+			msg:    fmt.Sprintf("Too many uploaded files, the maximum is %d", l.MaxFiles),
+		}
+	}
+	if !l.allows(contentType) {
+		return &UploadError{
+			Status:   http.StatusUnsupportedMediaType,
+			Filename: filename,
+			msg:      fmt.Sprintf("Unsupported media type %q of uploaded file", contentType),
+		}
+	}
+	if l.MaxFileSize > 0 && size > l.MaxFileSize {
+		return l.fileTooLarge(filename)
+	}
+	return nil
+}
+
+func (l *UploadLimits) fileTooLarge(filename string) *UploadError {
+	return &UploadError{
+		Status:   http.StatusRequestEntityTooLarge,
+		Filename: filename,
+		msg:      fmt.Sprintf("Uploaded file larger than %d bytes", l.MaxFileSize),
+	}
+}
+
+// allows reports whether a file of the content type may be uploaded. The type is the one
+// declared by the client, the files without a type being "application/octet-stream".
+func (l *UploadLimits) allows(contentType string) bool {
+	if len(l.AllowedTypes) == 0 {
+		return true
+	}
+	return binding.MatchMediaType(contentType, l.AllowedTypes)
+}
+
+// uploadSettings returns the memory limit and the upload limits of the app,
+// or the defaults if the context is not bound to an app.
+func (c *Context) uploadSettings() (maxMemory int64, limits *UploadLimits) {
+	if c.Mel == nil {
+		return defaultMultipartMemory, &UploadLimits{}
+	}
+	maxMemory = c.Mel.MaxMultipartMemory
+	if maxMemory <= 0 {
+		maxMemory = defaultMultipartMemory
+	}
+	return maxMemory, &c.Mel.Uploads
+}
+
+// parseMultipartForm parses the multipart form of the request once, with the memory limit of the app,
+// and checks its files against the upload limits of the app.
+func (c *Context) parseMultipartForm() error {
+	if c.multipartParsed {
+		return c.multipartErr
+	}
+	c.multipartParsed = true
+
+	maxMemory, limits := c.limitBody()
+	err := c.Request.ParseMultipartForm(maxMemory)
+	if err == nil {
+		err = limits.check(c.Request.MultipartForm)
+	}
+	c.multipartErr = tooLarge(err)
+	return c.multipartErr
+}
+
+// limitBody limits the size of the body of a multipart request, see UploadLimits.MaxSize,
+// and returns the settings of the app.
+func (c *Context) limitBody() (maxMemory int64, limits *UploadLimits) {
+	maxMemory, limits = c.uploadSettings()
+	if max := limits.maxSize(maxMemory); max > 0 && c.Request.Body != nil {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max)
+	}
+	return maxMemory, limits
+}
+
+// tooLarge returns errFormTooLarge if err tells that the multipart request is too large.
+func tooLarge(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if err == multipart.ErrMessageTooLarge || errors.As(err, &maxBytesErr) {
+		return errFormTooLarge
+	}
+	return err
+}
+
+// uploadFailed aborts the request with the status of an UploadError, or else with 400 Bad Request.
+func (c *Context) uploadFailed(err error) error {
+	status := http.StatusBadRequest
+	if e, ok := err.(*UploadError); ok {
+		status = e.Status
+	}
+	c.AbortWithError(status, err).Type = ErrorTypeBind
+	return err
+}
+
+// MultipartForm returns the multipart form of the request, parsed with the memory limit of the app:
+// the files which exceed it are stored in temporary files, see Mel.MaxMultipartMemory.
+// If the request is not a valid multipart form, or if its files exceed the upload limits of the app,
+// the request is aborted with 400 Bad Request, or with the status of the UploadError, see Mel.Uploads.
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if err := c.parseMultipartForm(); err != nil {
+		return nil, c.uploadFailed(err)
+	}
+	return c.Request.MultipartForm, nil
+}
+
+// FormFile returns the first file uploaded for the form field, see MultipartForm.
+// It returns http.ErrMissingFile, without aborting the request, if there is none.
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	if files := form.File[name]; len(files) > 0 {
+		return files[0], nil
+	}
+	return nil, http.ErrMissingFile
+}
+
+// SaveUploadedFile writes an uploaded file to dst, creating its directory if needed.
+// The name of the file is chosen by the client: dst must not be built from it,
+// except from its filepath.Base.
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, src)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// UploadPart is a part of a multipart request read by Context.StreamMultipart.
+// Reading a file fails with an UploadError once it exceeds the maximum file size of the app.
+type UploadPart struct {
+	*multipart.Part
+	remaining int64 // the bytes which may still be read, or -1 if unlimited
+	limits    *UploadLimits
+}
+
+func (p *UploadPart) Read(b []byte) (int, error) {
+	if p.remaining < 0 {
+		return p.Part.Read(b)
+	}
+	// read one more byte than allowed, to tell a file of the maximum size from a larger one
+	if int64(len(b)) > p.remaining+1 {
+		b = b[:p.remaining+1]
+	}
+	n, err := p.Part.Read(b)
+	if int64(n) > p.remaining {
+		n = int(p.remaining)
+		p.remaining = 0
+		return n, p.limits.fileTooLarge(p.FileName())
+	}
+	p.remaining -= int64(n)
+	return n, err
+}
+
+// StreamMultipart reads the parts of a multipart request as they are received, without storing
+// the files in memory or on disk, and calls fn with each of them, e.g. to stream large uploads
+// to their storage. A part can only be read until fn returns.
+//
+// The files are checked against the upload limits of the app as they are read, see Mel.Uploads.
+// If the request is not a valid multipart form, or if fn returns an UploadError or the error of
+// reading a request larger than UploadLimits.MaxSize, the request is aborted as by MultipartForm.
+// The other errors returned by fn stop the reading and are returned as is.
+func (c *Context) StreamMultipart(fn func(part *UploadPart) error) error {
+	_, limits := c.limitBody()
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return c.uploadFailed(err)
+	}
+
+	var files int
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return c.uploadFailed(tooLarge(err))
+		}
+
+		p := &UploadPart{Part: part, remaining: -1, limits: limits}
+		if filename := part.FileName(); len(filename) > 0 {
+			files++
+			if err := limits.checkFile(files, filename, part.Header.Get("Content-Type"), 0); err != nil {
+				return c.uploadFailed(err)
+			}
+			if limits.MaxFileSize > 0 {
+				p.remaining = limits.MaxFileSize
+			}
+		}
+
+		err = fn(p)
+		part.Close()
+		if err != nil {
+			var uploadErr *UploadError
+			if errors.As(tooLarge(err), &uploadErr) {
+				return c.uploadFailed(uploadErr)
+			}
+			return err
+		}
+	}
+}
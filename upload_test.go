@@ -0,0 +1,263 @@
+package mel
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type uploadFile struct {
+	field, filename, contentType, content string
+}
+
+// performUpload posts a multipart form with a "title" value and the files.
+func performUpload(r http.Handler, files ...uploadFile) *httptest.ResponseRecorder {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	mw.WriteField("title", "holidays")
+	for _, f := range files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="`+f.field+`"; filename="`+f.filename+`"`)
+		if len(f.contentType) > 0 {
+			h.Set("Content-Type", f.contentType)
+		}
+		w, _ := mw.CreatePart(h)
+		w.Write([]byte(f.content))
+	}
+	mw.Close()
+
+	req, _ := http.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestContextFormFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	app := New()
+	app.Post("/upload", func(c *Context) {
+		file, err := c.FormFile("photo")
+		if err != nil {
+			return
+		}
+		assert.Equal(t, "holidays", c.PostForm("title"))
+		assert.NoError(t, c.SaveUploadedFile(file, filepath.Join(dir, "photos", filepath.Base(file.Filename))))
+
+		_, err = c.FormFile("missing")
+		assert.Equal(t, http.ErrMissingFile, err)
+
+		form, err := c.MultipartForm()
+		assert.NoError(t, err)
+		c.Text(200, "%d", len(form.File["photo"]))
+	})
+
+	w := performUpload(app,
+		uploadFile{"photo", "beach.png", "image/png", "sand"},
+		uploadFile{"photo", "sea.png", "image/png", "waves"},
+	)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "2", w.Body.String())
+	content, err := ioutil.ReadFile(filepath.Join(dir, "photos", "beach.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, "sand", string(content))
+
+	// not a multipart form
+	w = performRequest(app, "POST", "/upload")
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestContextUploadLimits(t *testing.T) {
+	items := []struct {
+		limits UploadLimits
+		files  []uploadFile
+		status int
+	}{
+		{UploadLimits{}, []uploadFile{{"a", "a.bin", "", "data"}, {"b", "b.gif", "image/gif", "gif"}}, 200},
+		{UploadLimits{MaxFiles: 1}, []uploadFile{{"a", "a.png", "image/png", "png"}}, 200},
+		{UploadLimits{MaxFiles: 1}, []uploadFile{{"a", "a.png", "image/png", "png"}, {"a", "b.png", "image/png", "png"}}, 413},
+		{UploadLimits{MaxFileSize: 4}, []uploadFile{{"a", "a.txt", "text/plain", "four"}}, 200},
+		{UploadLimits{MaxFileSize: 4}, []uploadFile{{"a", "a.txt", "text/plain", "fives"}}, 413},
+		{UploadLimits{AllowedTypes: []string{"image/*", "application/pdf"}}, []uploadFile{{"a", "a.png", "Image/PNG", "png"}, {"b", "b.pdf", "application/pdf; x=y", "pdf"}}, 200},
+		{UploadLimits{AllowedTypes: []string{"image/*"}}, []uploadFile{{"a", "a.txt", "text/plain", "txt"}}, 415},
+		{UploadLimits{AllowedTypes: []string{"image/png"}}, []uploadFile{{"a", "a.bin", "", "data"}}, 415},
+	}
+
+	for i, item := range items {
+		for _, mode := range []string{"form", "stream", "bind"} {
+			app := New()
+			app.Uploads = item.limits
+			app.Post("/upload", func(c *Context) {
+				switch mode {
+				case "form":
+					if _, err := c.MultipartForm(); err != nil {
+						return
+					}
+				case "stream":
+					err := c.StreamMultipart(func(part *UploadPart) error {
+						_, err := io.Copy(ioutil.Discard, part)
+						return err
+					})
+					if err != nil {
+						return
+					}
+				case "bind":
+					var obj struct {
+						Title string `form:"title"`
+					}
+					if c.Bind(&obj) != nil {
+						return
+					}
+					assert.Equal(t, "holidays", obj.Title)
+				}
+				c.Text(200, "ok")
+			})
+
+			w := performUpload(app, item.files...)
+			assert.Equal(t, item.status, w.Code, "%d %s", i, mode)
+		}
+	}
+}
+
+type typedUpload struct {
+	File *multipart.FileHeader `form:"file"`
+}
+
+func TestTypedUploadLimits(t *testing.T) {
+	app := New()
+	app.Uploads = UploadLimits{MaxFileSize: 10, AllowedTypes: []string{"image/png"}}
+	app.Post("/upload", func(c *Context, req *typedUpload) (Object, error) {
+		return Object{"size": req.File.Size}, nil
+	})
+
+	w := performUpload(app, uploadFile{"file", "a.png", "image/png", "png"})
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"size":3}`, w.Body.String())
+
+	w = performUpload(app, uploadFile{"file", "a.png", "image/png", strings.Repeat("x", 100)})
+	assert.Equal(t, 413, w.Code)
+
+	w = performUpload(app, uploadFile{"file", "a.bin", "", strings.Repeat("x", 100)})
+	assert.Equal(t, 415, w.Code)
+}
+
+func TestContextMultipartMemory(t *testing.T) {
+	app := New()
+	app.MaxMultipartMemory = 16
+	app.Post("/upload", func(c *Context) {
+		// the values are not stored on disk, unlike the files, and
+		// net/http allows them 10 MB more than the memory limit
+		_, err := c.MultipartForm()
+		assert.Equal(t, errFormTooLarge, err)
+	})
+
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	mw.WriteField("text", strings.Repeat("x", 10<<20+64))
+	mw.Close()
+	req, _ := http.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	assert.Equal(t, 413, w.Code)
+}
+
+func TestContextUploadMaxSize(t *testing.T) {
+	limits := UploadLimits{MaxFiles: 2, MaxFileSize: 1 << 20}
+	assert.Equal(t, int64(2*(1<<20+partOverhead)+32<<20+10<<20), limits.maxSize(32<<20))
+	limits.MaxSize = 100
+	assert.Equal(t, int64(100), limits.maxSize(32<<20))
+	assert.Equal(t, int64(0), (&UploadLimits{MaxFileSize: 1 << 20}).maxSize(32<<20))
+
+	// the body is read no further than the limit
+	for _, mode := range []string{"form", "stream"} {
+		app := New()
+		app.Uploads.MaxSize = 1 << 10
+		var read int64
+		app.Post("/upload", func(c *Context) {
+			if mode == "form" {
+				c.MultipartForm()
+				return
+			}
+			c.StreamMultipart(func(part *UploadPart) error {
+				n, err := io.Copy(ioutil.Discard, part)
+				read += n
+				return err
+			})
+		})
+
+		w := performUpload(app, uploadFile{"a", "a.bin", "", strings.Repeat("x", 1<<10)})
+		assert.Equal(t, 413, w.Code, mode)
+		assert.True(t, read < 1<<10, mode)
+	}
+}
+
+func TestContextPostFormError(t *testing.T) {
+	app := New()
+	app.Uploads.MaxFiles = 1
+	app.Post("/upload", func(c *Context) {
+		// the values are still available, the error is recorded once
+		assert.Equal(t, "holidays", c.PostForm("title"))
+		assert.Equal(t, "", c.PostForm("missing"))
+		if assert.Len(t, c.Errors, 1) {
+			assert.True(t, c.Errors[0].IsType(ErrorTypeBind))
+			assert.IsType(t, &UploadError{}, c.Errors[0].Err)
+		}
+		c.Text(200, "ok")
+	})
+
+	w := performUpload(app, uploadFile{"a", "a.png", "image/png", "png"}, uploadFile{"b", "b.png", "image/png", "png"})
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestContextStreamMultipart(t *testing.T) {
+	app := New()
+	app.Uploads.MaxFileSize = 8
+	errStorage := errors.New("storage failure")
+	app.Post("/upload", func(c *Context) {
+		var names []string
+		err := c.StreamMultipart(func(part *UploadPart) error {
+			content, err := ioutil.ReadAll(part)
+			if err != nil {
+				return err
+			}
+			names = append(names, part.FormName()+"="+string(content))
+			if part.FileName() == "fail.txt" {
+				return errStorage
+			}
+			return nil
+		})
+		switch err {
+		case nil:
+			c.Text(200, "%s", strings.Join(names, ","))
+		case errStorage:
+			c.Text(500, "%s", err)
+		}
+	})
+
+	w := performUpload(app, uploadFile{"doc", "a.txt", "text/plain", "12345678"})
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "title=holidays,doc=12345678", w.Body.String())
+
+	w = performUpload(app, uploadFile{"doc", "a.txt", "text/plain", "123456789"})
+	assert.Equal(t, 413, w.Code)
+
+	// the errors of fn are returned without aborting the request
+	w = performUpload(app, uploadFile{"doc", "fail.txt", "text/plain", "x"})
+	assert.Equal(t, 500, w.Code)
+	assert.Equal(t, "storage failure", w.Body.String())
+}
@@ -0,0 +1,113 @@
+package mel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var errURLParamsNotPaired = errors.New("URL params must be key/value pairs")
+
+// URL builds the URL of the route named name, see Endpoint.Name.
+// The pairs are alternating param keys and values, e.g. URL("user", "id", 42).
+// A key may be given with or without its ':' or '*' prefix.
+// The params of the route pattern are filled in with the values, escaped,
+// and the remaining pairs are appended as the query string.
+// Optional parts of the pattern are only filled in if all their params are given.
+// An error is returned if a param is missing, or if its value does not match the param,
+// e.g. the regular expression of "(:id[0-9]+)" or the constraint of ":id<int>".
+func (r *Router) URL(name string, pairs ...interface{}) (string, error) {
+	e, ok := r.served().names[name]
+	if !ok {
+		return "", fmt.Errorf("Route %q does not exist", name)
+	}
+	if len(pairs)%2 != 0 {
+		return "", errURLParamsNotPaired
+	}
+
+	values := make(url.Values, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok || len(key) == 0 {
+			return "", fmt.Errorf("Invalid URL param key %v", pairs[i])
+		}
+		if key[0] == ':' || key[0] == '*' {
+			key = key[1:]
+		}
+		values.Add(key, fmt.Sprint(pairs[i+1]))
+	}
+
+	// the longest pattern whose params are all given, if the pattern has optional parts
+	pattern := e.path
+	if patterns := expandPath(e.path); len(patterns) > 1 {
+		pattern = patterns[len(patterns)-1]
+		for _, p := range patterns {
+			if hasURLParams(p, values) {
+				pattern = p
+				break
+			}
+		}
+	}
+
+	return buildURL(pattern, values)
+}
+
+func hasURLParams(pattern string, values url.Values) bool {
+	for _, n := range parsePath(pattern) {
+		if n.kind != staticNode && len(values[n.segment[1:]]) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func buildURL(pattern string, values url.Values) (string, error) {
+	var buf bytes.Buffer
+	for _, n := range parsePath(pattern) {
+		if n.kind == staticNode {
+			buf.WriteString(n.segment)
+			continue
+		}
+
+		key := n.segment[1:]
+		vs := values[key]
+		if len(vs) == 0 {
+			return "", fmt.Errorf("URL param %q of %s is missing", key, pattern)
+		}
+		value := vs[0]
+		if len(vs) == 1 {
+			delete(values, key)
+		} else {
+			values[key] = vs[1:]
+		}
+
+		switch n.kind {
+		case namedNode, regexNode:
+			if len(value) == 0 || strings.IndexByte(value, '/') >= 0 {
+				return "", fmt.Errorf("URL param %q of %s is invalid: %q", key, pattern, value)
+			}
+			if n.validate != nil && !n.validate(value) {
+				return "", fmt.Errorf("URL param %q of %s does not satisfy <%s>: %q", key, pattern, n.constraint, value)
+			}
+			if n.regexp != nil && !n.regexp.MatchString(value) {
+				return "", fmt.Errorf("URL param %q of %s does not match %s: %q", key, pattern, n.regexp, value)
+			}
+			buf.WriteString(url.PathEscape(value))
+		case anyNode:
+			for i, s := range strings.Split(value, "/") {
+				if i > 0 {
+					buf.WriteByte('/')
+				}
+				buf.WriteString(url.PathEscape(s))
+			}
+		}
+	}
+
+	if len(values) > 0 {
+		buf.WriteByte('?')
+		buf.WriteString(values.Encode())
+	}
+	return buf.String(), nil
+}
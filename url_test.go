@@ -0,0 +1,122 @@
+package mel
+
+import (
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURL(t *testing.T) {
+	router := New()
+	router.Get("/", func() {}).Name("home")
+	router.Get("/users/:id", func() {}).Name("user")
+	router.Get("/users/(:id[0-9]+)/edit", func() {}).Name("user.edit")
+	router.Get("/static/*filepath", func() {}).Name("static")
+	router.Group("/posts").Get("/:year-:month", func() {}).Name("archive")
+	router.Get("/files/:name.:ext?", func() {}).Name("file")
+
+	url, err := router.URL("home")
+	assert.NoError(t, err)
+	assert.Equal(t, "/", url)
+
+	url, err = router.URL("user", "id", 42)
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/42", url)
+
+	url, err = router.URL("user", ":id", "manu ma", "tab", "posts", "page", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/manu%20ma?page=2&tab=posts", url)
+
+	url, err = router.URL("user.edit", "id", 42)
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/42/edit", url)
+
+	url, err = router.URL("static", "filepath", "css/main app.css")
+	assert.NoError(t, err)
+	assert.Equal(t, "/static/css/main%20app.css", url)
+
+	url, err = router.URL("archive", "year", 2017, "month", "05")
+	assert.NoError(t, err)
+	assert.Equal(t, "/posts/2017-05", url)
+
+	url, err = router.URL("file", "name", "main", "ext", "go")
+	assert.NoError(t, err)
+	assert.Equal(t, "/files/main.go", url)
+
+	url, err = router.URL("file", "name", "LICENSE")
+	assert.NoError(t, err)
+	assert.Equal(t, "/files/LICENSE", url)
+}
+
+func TestURLErrors(t *testing.T) {
+	router := New()
+	router.Get("/users/:id", func() {}).Name("user")
+	router.Get("/users/(:id[0-9]+)/edit", func() {}).Name("user.edit")
+	router.Get("/orders/:id<int>", func() {}).Name("order")
+
+	_, err := router.URL("unknown")
+	assert.Error(t, err)
+
+	_, err = router.URL("user")
+	assert.Error(t, err)
+
+	_, err = router.URL("user", "id")
+	assert.Error(t, err)
+
+	_, err = router.URL("user", 1, 2)
+	assert.Error(t, err)
+
+	_, err = router.URL("user", "id", "a/b")
+	assert.Error(t, err)
+
+	_, err = router.URL("user.edit", "id", "abc")
+	assert.Error(t, err)
+
+	_, err = router.URL("user.edit", "id", "12a")
+	assert.Error(t, err)
+
+	_, err = router.URL("order", "id", "12a")
+	assert.Error(t, err)
+
+	url, err := router.URL("order", "id", 12)
+	assert.NoError(t, err)
+	assert.Equal(t, "/orders/12", url)
+}
+
+func TestRouteNameConflict(t *testing.T) {
+	router := New()
+	e := router.Get("/users/:id", func() {}).Name("user")
+	assert.NotPanics(t, func() { e.Name("user") })
+	assert.Panics(t, func() { router.Get("/people/:id", func() {}).Name("user") })
+	assert.Panics(t, func() { e.Name("") })
+
+	e.Name("member")
+	_, err := router.URL("user", "id", 1)
+	assert.Error(t, err)
+	url, _ := router.URL("member", "id", 1)
+	assert.Equal(t, "/users/1", url)
+}
+
+func TestURLTemplateFunc(t *testing.T) {
+	router := New()
+	router.Get("/users/:id", func() {}).Name("user")
+	router.Get("/page", func(c *Context) { c.HTML(200, "", 42) })
+
+	router.SetTemplate(template.Must(template.New("").Funcs(router.FuncMap()).Parse(`<a href="{{url "user" "id" .}}">`)))
+	w := performRequest(router, "GET", "/page")
+	assert.Equal(t, `<a href="/users/42">`, w.Body.String())
+
+	dir, err := ioutil.TempDir("", "mel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "page.tmpl")
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`{{url "user" "id" . "tab" "posts"}}`), 0644))
+
+	router.LoadTemplateGlob(filepath.Join(dir, "*.tmpl"))
+	w = performRequest(router, "GET", "/page")
+	assert.Equal(t, `/users/42?tab=posts`, w.Body.String())
+}
@@ -1,15 +1,37 @@
 package mel
 
 import (
+	"encoding/xml"
 	"path"
+	"sort"
 	"os"
 	"runtime"
 	"reflect"
+	"strings"
 )
 
 // Object represents "object" in JSON.
 type Object map[string]interface{}
 
+// MarshalXML allows Object to be rendered as XML, with its keys as elements, sorted.
+func (o Object) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "map"}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(o))
+	for key := range o {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := e.EncodeElement(o[key], xml.StartElement{Name: xml.Name{Local: key}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
 // Array represents "array" in JSON.
 type Array []interface{}
 
@@ -22,7 +44,8 @@ func lastChar(str string) uint8 {
 }
 
 func nameOfFunction(f interface{}) string {
-	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+	// the method values are named after their method with a "-fm" suffix
+	return strings.TrimSuffix(runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name(), "-fm")
 }
 
 func joinPaths(absolutePath, relativePath string) string {